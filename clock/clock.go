@@ -9,10 +9,39 @@ import "time"
 // A Clock represents a cronometer which can be queried for a time.Time value.
 type Clock interface {
 
-	// Now returns the current time according to this Clock
+	// Now returns the current time according to this Clock. If the
+	// time cannot be read, Now returns the zero time.Time.
 	Now() time.Time
 }
 
+// A CheckedClock is a Clock which can report a failure to read its
+// underlying cronometer.
+type CheckedClock interface {
+	Clock
+
+	// NowErr returns the current time according to this Clock, or an
+	// error if the time could not be read.
+	NowErr() (time.Time, error)
+}
+
+// A ResolutionClock is a Clock which can report the resolution, or precision,
+// of its readings.
+type ResolutionClock interface {
+	Clock
+
+	// Resolution returns the smallest interval this Clock can distinguish,
+	// or an error if it could not be read.
+	Resolution() (time.Duration, error)
+}
+
+// A Waiter is a Clock which can block the caller until it reaches a given time.
+type Waiter interface {
+	Clock
+
+	// SleepUntil blocks until this Clock reads t or later.
+	SleepUntil(t time.Time) error
+}
+
 // A Timer represents an amount of time that elapsed according to a Clock.
 type Timer interface {
 
@@ -21,5 +50,5 @@ type Timer interface {
 	Elapsed() time.Duration
 
 	// Clock returns the underlying clock which powers this timer.
-	// Clock() Clock
+	Clock() Clock
 }
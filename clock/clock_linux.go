@@ -1,6 +1,10 @@
 package clock
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
 	"syscall"
 	"time"
 	"unsafe"
@@ -21,6 +25,10 @@ const (
 	CLOCK_BOOTTIME_ALARM
 )
 
+// TIMER_ABSTIME causes clock_nanosleep to interpret its argument as an
+// absolute time, from /usr/include/linux/time.h
+const TIMER_ABSTIME = 1
+
 // Available Clocks
 var (
 	// System-wide clock that measures real (i.e., wall-clock) time.
@@ -37,6 +45,36 @@ var (
 
 	// High-resolution per-process timer from the CPU.
 	Process Clock = &clock{CLOCK_PROCESS_CPUTIME_ID}
+
+	// Thread-specific CPU-time clock. As goroutines are not bound to an OS
+	// thread, readings are only meaningful when the caller has called
+	// runtime.LockOSThread.
+	Thread Clock = &clock{CLOCK_THREAD_CPUTIME_ID}
+
+	// Similar to Monotonic, but provides access to a raw hardware-based time
+	// that is not subject to NTP adjustments or the incremental adjustments
+	// performed by adjtime(3).
+	MonotonicRaw Clock = &clock{CLOCK_MONOTONIC_RAW}
+
+	// A faster but less precise version of Realtime. Use when you need very
+	// fast, but not fine-grained timestamps.
+	RealtimeCoarse Clock = &clock{CLOCK_REALTIME_COARSE}
+
+	// A faster but less precise version of Monotonic. Use when you need very
+	// fast, but not fine-grained timestamps.
+	MonotonicCoarse Clock = &clock{CLOCK_MONOTONIC_COARSE}
+
+	// Identical to Monotonic, except it also includes any time that the
+	// system is suspended.
+	Boottime Clock = &clock{CLOCK_BOOTTIME}
+
+	// Like Realtime, but not settable. Timers on this clock will wake the
+	// system if it is suspended.
+	RealtimeAlarm Clock = &clock{CLOCK_REALTIME_ALARM}
+
+	// Like Boottime, but not settable. Timers on this clock will wake the
+	// system if it is suspended.
+	BoottimeAlarm Clock = &clock{CLOCK_BOOTTIME_ALARM}
 )
 
 // Available Timers
@@ -45,15 +83,163 @@ var (
 	Uptime Timer = &timer{clock: clock{CLOCK_BOOTTIME}}
 )
 
+// clocks maps each clock id to its name and Clock.
+var clocks = [...]struct {
+	name string
+	Clock
+}{
+	CLOCK_REALTIME:           {"realtime", Realtime},
+	CLOCK_MONOTONIC:          {"monotonic", Monotonic},
+	CLOCK_PROCESS_CPUTIME_ID: {"process_cputime_id", Process},
+	CLOCK_THREAD_CPUTIME_ID:  {"thread_cputime_id", Thread},
+	CLOCK_MONOTONIC_RAW:      {"monotonic_raw", MonotonicRaw},
+	CLOCK_REALTIME_COARSE:    {"realtime_coarse", RealtimeCoarse},
+	CLOCK_MONOTONIC_COARSE:   {"monotonic_coarse", MonotonicCoarse},
+	CLOCK_BOOTTIME:           {"boottime", Boottime},
+	CLOCK_REALTIME_ALARM:     {"realtime_alarm", RealtimeAlarm},
+	CLOCK_BOOTTIME_ALARM:     {"boottime_alarm", BoottimeAlarm},
+}
+
+// Clocks returns every Clock provided by this package, in clock id order.
+func Clocks() []Clock {
+	var cs []Clock
+	for _, c := range clocks {
+		cs = append(cs, c.Clock)
+	}
+	return cs
+}
+
+// ErrUnknownClock is returned by Lookup when no Clock matches the name.
+var ErrUnknownClock = errors.New("clock: unknown clock")
+
+// Lookup returns the Clock with the given name. Names are the clock id
+// constants in lower case, with or without the clock_ prefix, eg.
+// "monotonic_raw" or "CLOCK_MONOTONIC_RAW".
+func Lookup(name string) (Clock, error) {
+	name = strings.TrimPrefix(strings.ToLower(name), "clock_")
+	for _, c := range clocks {
+		if c.name == name {
+			return c.Clock, nil
+		}
+	}
+	return nil, ErrUnknownClock
+}
+
+// Available returns nil if the clock id can be read on this host, or the
+// error reported by clock_gettime(2) otherwise.
+func Available(id int) error {
+	_, err := gettime(uintptr(id))
+	return err
+}
+
+// Flag is a flag.Value which selects a Clock by name. Set fails if the
+// name is unknown, or the clock is not available on this host.
+//
+//	c := clock.Flag{Clock: clock.Monotonic}
+//	flag.Var(&c, "clock", "clock to use")
+type Flag struct {
+	Clock
+}
+
+func (f *Flag) String() string {
+	if s, ok := f.Clock.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return ""
+}
+
+func (f *Flag) Set(name string) error {
+	c, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	if _, err := c.(CheckedClock).NowErr(); err != nil {
+		return err
+	}
+	f.Clock = c
+	return nil
+}
+
 type clock struct {
 	clockid uintptr
 }
 
 func (c *clock) Now() time.Time {
-	var ts syscall.Timespec
-	syscall.Syscall(syscall.SYS_CLOCK_GETTIME, c.clockid, uintptr(unsafe.Pointer(&ts)), 0)
+	t, _ := c.NowErr()
+	return t
+}
+
+func (c *clock) NowErr() (time.Time, error) {
+	ts, err := gettime(c.clockid)
+	if err != nil {
+		return time.Time{}, err
+	}
 	sec, nsec := ts.Unix()
-	return time.Unix(sec, nsec)
+	return time.Unix(sec, nsec), nil
+}
+
+// Resolution returns the resolution of this clock as reported by clock_getres(2).
+func (c *clock) Resolution() (time.Duration, error) {
+	var ts syscall.Timespec
+	_, _, e := syscall.Syscall(syscall.SYS_CLOCK_GETRES, c.clockid, uintptr(unsafe.Pointer(&ts)), 0)
+	if e != 0 {
+		return 0, os.NewSyscallError("clock_getres", e)
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+// SleepUntil blocks until the clock reads t or later using clock_nanosleep(2)
+// with TIMER_ABSTIME. As the deadline is absolute, a sleep on Realtime wakes
+// early or late if the wall clock is stepped, and a sleep on Boottime counts
+// time the system spends suspended. The calling goroutine occupies an
+// operating system thread for the duration of the sleep.
+func (c *clock) SleepUntil(t time.Time) error {
+	var ts syscall.Timespec
+	if ns := t.UnixNano(); ns > 0 {
+		// negative deadlines are invalid, but are always in the past.
+		ts = syscall.NsecToTimespec(ns)
+	}
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_CLOCK_NANOSLEEP, c.clockid, TIMER_ABSTIME, uintptr(unsafe.Pointer(&ts)), 0, 0, 0)
+		switch e {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			// interrupted by a signal, the deadline is absolute so retry.
+		default:
+			return os.NewSyscallError("clock_nanosleep", e)
+		}
+	}
+}
+
+// String returns the name of the clock, as accepted by Lookup.
+func (c *clock) String() string {
+	if c.clockid < uintptr(len(clocks)) {
+		return clocks[c.clockid].name
+	}
+	if name, ok := cpuClockName(c.clockid); ok {
+		return name
+	}
+	return fmt.Sprintf("clock(%d)", c.clockid)
+}
+
+// gettime reads clockid using the vDSO if available, or the clock_gettime
+// syscall otherwise.
+func gettime(clockid uintptr) (syscall.Timespec, error) {
+	if fn := vdso(); fn != 0 {
+		return vdsoGettime(fn, clockid)
+	}
+	return sysGettime(clockid)
+}
+
+// sysGettime reads clockid using the clock_gettime syscall.
+func sysGettime(clockid uintptr) (syscall.Timespec, error) {
+	var ts syscall.Timespec
+	_, _, e := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, clockid, uintptr(unsafe.Pointer(&ts)), 0)
+	if e != 0 {
+		return ts, os.NewSyscallError("clock_gettime", e)
+	}
+	return ts, nil
 }
 
 type timer struct {
@@ -65,3 +251,5 @@ var epoch = time.Unix(0, 0)
 func (t *timer) Elapsed() time.Duration {
 	return t.Now().Sub(epoch)
 }
+
+func (t *timer) Clock() Clock { return &t.clock }
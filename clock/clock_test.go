@@ -1,7 +1,11 @@
 package clock
 
-import "testing"
-import "time"
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
 
 func TestClockMonotonic(t *testing.T) {
 	now := Monotonic.Now()
@@ -34,3 +38,107 @@ func TestTimerUptime(t *testing.T) {
 	}
 	t.Log("Boottime", bt)
 }
+
+func TestClockResolution(t *testing.T) {
+	clocks := map[string]Clock{
+		"Realtime":        Realtime,
+		"Monotonic":       Monotonic,
+		"Process":         Process,
+		"Thread":          Thread,
+		"MonotonicRaw":    MonotonicRaw,
+		"RealtimeCoarse":  RealtimeCoarse,
+		"MonotonicCoarse": MonotonicCoarse,
+		"Boottime":        Boottime,
+	}
+	for name, c := range clocks {
+		rc, ok := c.(ResolutionClock)
+		if !ok {
+			t.Fatalf("%s: does not implement ResolutionClock", name)
+		}
+		res, err := rc.Resolution()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if res <= 0 {
+			t.Fatalf("%s: resolution was %v, expecting non zero", name, res)
+		}
+		t.Log(name, res)
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	for _, id := range []int{CLOCK_REALTIME, CLOCK_MONOTONIC, CLOCK_BOOTTIME} {
+		if err := Available(id); err != nil {
+			t.Fatalf("Available(%d): %v", id, err)
+		}
+	}
+	if err := Available(-1); err == nil {
+		t.Fatal("Available(-1): expected error")
+	}
+}
+
+func TestNowErr(t *testing.T) {
+	c := &clock{^uintptr(0) >> 1}
+	if _, err := c.NowErr(); err == nil {
+		t.Fatal("expected error from invalid clock")
+	}
+	if _, err := c.Resolution(); err == nil {
+		t.Fatal("expected error from invalid clock resolution")
+	}
+	if now := c.Now(); !now.IsZero() {
+		t.Fatalf("Now: got %v, expected zero time", now)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		want Clock
+	}{
+		{"realtime", Realtime},
+		{"monotonic_raw", MonotonicRaw},
+		{"CLOCK_BOOTTIME", Boottime},
+		{"Process_CPUTime_ID", Process},
+	}
+	for _, tt := range tests {
+		got, err := Lookup(tt.name)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Fatalf("Lookup(%q): got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if _, err := Lookup("sundial"); err != ErrUnknownClock {
+		t.Fatalf("Lookup(%q): got %v, want %v", "sundial", err, ErrUnknownClock)
+	}
+}
+
+func TestFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	c := Flag{Clock: Monotonic}
+	fs.Var(&c, "clock", "clock to use")
+	if err := fs.Parse([]string{"-clock", "monotonic_raw"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Clock != MonotonicRaw {
+		t.Fatalf("got %v, want %v", c.Clock, MonotonicRaw)
+	}
+	if got := c.String(); got != "monotonic_raw" {
+		t.Fatalf("String: got %q", got)
+	}
+	if err := fs.Parse([]string{"-clock", "sundial"}); err == nil {
+		t.Fatal("expected error for unknown clock")
+	}
+}
+
+func TestClocks(t *testing.T) {
+	cs := Clocks()
+	if len(cs) != CLOCK_BOOTTIME_ALARM+1 {
+		t.Fatalf("got %d clocks, want %d", len(cs), CLOCK_BOOTTIME_ALARM+1)
+	}
+	if cs[CLOCK_MONOTONIC_RAW] != MonotonicRaw {
+		t.Fatalf("got %v, want %v", cs[CLOCK_MONOTONIC_RAW], MonotonicRaw)
+	}
+}
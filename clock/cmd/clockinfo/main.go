@@ -0,0 +1,98 @@
+// Clockinfo reports every clock the kernel provides, whether it is available
+// on this host, its resolution, current reading, offset from CLOCK_REALTIME
+// and the cost of reading it.
+//
+// Usage
+//
+//	$GOPATH/bin/clockinfo [-json] [-n iterations]
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/davecheney/junk/clock"
+)
+
+var (
+	jsonOut = flag.Bool("json", false, "write JSON instead of a table")
+	iters   = flag.Int("n", 100000, "number of reads used to measure read cost")
+)
+
+// Info describes one clock.
+type Info struct {
+	Name       string        `json:"name"`
+	Available  bool          `json:"available"`
+	Error      string        `json:"error,omitempty"`
+	Resolution time.Duration `json:"resolution_ns"`
+	Reading    time.Duration `json:"reading_ns"`
+	Offset     time.Duration `json:"offset_ns"`
+	Cost       float64       `json:"cost_ns_per_op"`
+}
+
+func info(c clock.Clock) Info {
+	i := Info{Name: fmt.Sprint(c)}
+	rt, err := clock.Read(clock.Realtime)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now, err := clock.Read(c)
+	if err != nil {
+		i.Error = err.Error()
+		return i
+	}
+	i.Available = true
+	i.Reading = now.Duration()
+	i.Offset = now.Duration() - rt.Duration()
+	if rc, ok := c.(clock.ResolutionClock); ok {
+		if i.Resolution, err = rc.Resolution(); err != nil {
+			i.Error = err.Error()
+		}
+	}
+	i.Cost = cost(c, *iters)
+	return i
+}
+
+// cost returns the average time taken to read c, in nanoseconds.
+func cost(c clock.Clock, n int) float64 {
+	if n <= 0 {
+		return 0
+	}
+	sw := clock.NewTimer(clock.Monotonic)
+	sw.Start()
+	for i := 0; i < n; i++ {
+		c.Now()
+	}
+	return float64(sw.Stop()) / float64(n)
+}
+
+func main() {
+	flag.Parse()
+	var infos []Info
+	for _, c := range clock.Clocks() {
+		infos = append(infos, info(c))
+	}
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(infos); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "CLOCK\tAVAILABLE\tRESOLUTION\tREADING\tOFFSET\tCOST")
+	for _, i := range infos {
+		if !i.Available {
+			fmt.Fprintf(w, "%s\tno (%s)\t\t\t\t\n", i.Name, i.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tyes\t%v\t%v\t%v\t%.1f ns/op\n", i.Name, i.Resolution, i.Reading, i.Offset, i.Cost)
+	}
+	w.Flush()
+}
@@ -0,0 +1,39 @@
+// Timeit runs a command and reports the time and resources it used, like
+// time(1).
+//
+// Usage
+//
+//	$GOPATH/bin/timeit command [args...]
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+
+	"github.com/davecheney/junk/clock"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("timeit: ")
+	if len(os.Args) < 2 {
+		log.Fatal("usage: timeit command [args...]")
+	}
+	cmd := exec.Command(os.Args[1], os.Args[2:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	var err error
+	m := clock.Measure(func() { err = cmd.Run() })
+	c := m.Children
+	fmt.Fprintf(os.Stderr, "real\t%v\nuser\t%v\nsys\t%v\n", m.Wall, c.User, c.System)
+	fmt.Fprintf(os.Stderr, "maxrss\t%d KiB\n", c.MaxRSS/1024)
+	fmt.Fprintf(os.Stderr, "faults\t%d minor, %d major\n", c.MinorFaults, c.MajorFaults)
+	fmt.Fprintf(os.Stderr, "ctxsw\t%d voluntary, %d involuntary\n", c.VoluntarySwitches, c.InvoluntarySwitches)
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			os.Exit(exit.ExitCode())
+		}
+		log.Fatal(err)
+	}
+}
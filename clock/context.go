@@ -0,0 +1,108 @@
+package clock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithDeadline returns a copy of parent which is cancelled when c reads d
+// or later, when the returned cancel function is called, or when parent is
+// done, whichever happens first. Err returns context.DeadlineExceeded once
+// the deadline passes.
+//
+// Unlike context.WithDeadline, which uses the runtime's monotonic clock, a
+// deadline on Boottime counts time the system spends suspended and a
+// deadline on Realtime follows changes to the wall clock. On Linux,
+// Realtime, Monotonic, Boottime and the alarm clocks are waited for with a
+// timerfd on the runtime's network poller; other Clocks must be Waiters, and
+// only waits on a Manual clock are abandoned when the context is cancelled.
+// If c cannot wait, the returned context is cancelled immediately with
+// ErrCannotWait as its cause.
+//
+// The Deadline method of the returned context reports d converted to wall
+// time, as the time remaining on c when WithDeadline was called added to
+// time.Now, or the parent's deadline if that is earlier. Contexts derived
+// from it with context.WithDeadline see that estimate, not d.
+func WithDeadline(parent context.Context, c Clock, d time.Time) (context.Context, context.CancelFunc) {
+	inner, cancel := context.WithCancelCause(parent)
+	ctx := &clockCtx{
+		Context:  inner,
+		cancel:   cancel,
+		deadline: time.Now().Add(d.Sub(c.Now())),
+	}
+	if pd, ok := parent.Deadline(); ok && pd.Before(ctx.deadline) {
+		ctx.deadline = pd
+	}
+	fired, stop := wait(c, d)
+	go ctx.run(fired, stop)
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// WithTimeout returns WithDeadline(parent, c, c.Now().Add(timeout)).
+func WithTimeout(parent context.Context, c Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return WithDeadline(parent, c, c.Now().Add(timeout))
+}
+
+// wait returns a channel which receives nil when c reaches d, or an error if
+// c cannot wait, and a function which releases any resources held. Waits on
+// a timerfd or an afterFuncer such as Manual are abandoned by the function,
+// other Waiters sleep in a goroutine until d regardless.
+func wait(c Clock, d time.Time) (<-chan error, func()) {
+	if fired, stop, ok := timerfdWait(c, d); ok {
+		return fired, stop
+	}
+	fired := make(chan error, 1)
+	if af, ok := c.(afterFuncer); ok {
+		t := af.afterFuncAt(d, func() { fired <- nil })
+		return fired, func() { t.Stop() }
+	}
+	if _, ok := c.(Waiter); !ok {
+		fired <- ErrCannotWait
+		return fired, func() {}
+	}
+	go func() {
+		fired <- SleepUntil(c, d)
+	}()
+	return fired, func() {}
+}
+
+// clockCtx is a context which is cancelled by a deadline on a Clock.
+type clockCtx struct {
+	context.Context // from context.WithCancelCause(parent)
+	cancel          context.CancelCauseFunc
+	deadline        time.Time // wall time estimate of the clock deadline
+
+	mu      sync.Mutex // protects expired
+	expired bool       // the deadline passed before ctx was cancelled
+}
+
+func (c *clockCtx) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockCtx) Err() error {
+	err := c.Context.Err()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && c.expired {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func (c *clockCtx) run(fired <-chan error, stop func()) {
+	defer stop()
+	select {
+	case err := <-fired:
+		if err != nil {
+			c.cancel(err)
+			return
+		}
+		c.mu.Lock()
+		c.expired = c.Context.Err() == nil
+		c.mu.Unlock()
+		c.cancel(context.DeadlineExceeded)
+	case <-c.Done():
+	}
+}
@@ -0,0 +1,31 @@
+package clock
+
+import (
+	"os"
+	"time"
+)
+
+// timerfdWait is wait using a timerfd, or reports false if c cannot drive a
+// timerfd.
+func timerfdWait(c Clock, d time.Time) (<-chan error, func(), bool) {
+	tfd, err := newTimerFD(c, TFD_CLOEXEC|TFD_NONBLOCK)
+	if err != nil {
+		return nil, nil, false
+	}
+	fired := make(chan error, 1)
+	if err := tfd.SetAt(d, 0); err != nil {
+		tfd.Close()
+		fired <- err
+		return fired, func() {}, true
+	}
+	// A non blocking descriptor passed to os.NewFile is registered with
+	// the runtime's poller, so Read parks the goroutine rather than the
+	// thread, and Close wakes it.
+	f := os.NewFile(tfd.Fd(), "timerfd")
+	go func() {
+		var buf [8]byte
+		_, err := f.Read(buf[:])
+		fired <- err
+	}()
+	return fired, func() { f.Close() }, true
+}
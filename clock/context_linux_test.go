@@ -0,0 +1,119 @@
+package clock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithTimeout(t *testing.T) {
+	for _, c := range []Clock{Monotonic, Boottime, Realtime} {
+		ctx, cancel := WithTimeout(context.Background(), c, 10*time.Millisecond)
+		start := Monotonic.Now()
+		<-ctx.Done()
+		if d := Monotonic.Now().Sub(start); d < 5*time.Millisecond {
+			t.Fatalf("%v: done after %v, expected about 10ms", c, d)
+		}
+		if err := ctx.Err(); err != context.DeadlineExceeded {
+			t.Fatalf("%v: got %v, want %v", c, err, context.DeadlineExceeded)
+		}
+		cancel()
+		if err := ctx.Err(); err != context.DeadlineExceeded {
+			t.Fatalf("%v: after cancel got %v, want %v", c, err, context.DeadlineExceeded)
+		}
+	}
+}
+
+func TestWithDeadlineCancel(t *testing.T) {
+	ctx, cancel := WithDeadline(context.Background(), Boottime, Boottime.Now().Add(time.Hour))
+	select {
+	case <-ctx.Done():
+		t.Fatal("done before deadline")
+	default:
+	}
+	cancel()
+	<-ctx.Done()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithDeadlineParent(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, _ := WithTimeout(parent, Monotonic, time.Hour)
+	cancel()
+	<-ctx.Done()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithDeadlineDeadline(t *testing.T) {
+	for _, c := range []Clock{Monotonic, Boottime, NewManual(manualEpoch)} {
+		before := time.Now()
+		ctx, cancel := WithTimeout(context.Background(), c, time.Hour)
+		after := time.Now()
+		d, ok := ctx.Deadline()
+		cancel()
+		if !ok || d.Before(before.Add(time.Hour-time.Second)) || d.After(after.Add(time.Hour+time.Second)) {
+			t.Fatalf("%v: got deadline %v %v, want about an hour from %v", c, d, ok, before)
+		}
+	}
+	parent, pcancel := context.WithTimeout(context.Background(), time.Minute)
+	defer pcancel()
+	pd, _ := parent.Deadline()
+	ctx, cancel := WithTimeout(parent, Monotonic, time.Hour)
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || !d.Equal(pd) {
+		t.Fatalf("got deadline %v %v, want parent's %v", d, ok, pd)
+	}
+}
+
+func TestWithDeadlinePast(t *testing.T) {
+	ctx, cancel := WithDeadline(context.Background(), Realtime, Realtime.Now().Add(-time.Hour))
+	defer cancel()
+	<-ctx.Done()
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithTimeoutManual(t *testing.T) {
+	m := NewManual(manualEpoch)
+	ctx, cancel := WithTimeout(context.Background(), m, time.Hour)
+	defer cancel()
+	m.BlockUntil(1)
+	m.Advance(time.Hour - 1)
+	select {
+	case <-ctx.Done():
+		t.Fatal("done before deadline")
+	case <-time.After(5 * time.Millisecond):
+	}
+	m.Advance(1)
+	<-ctx.Done()
+	if err := ctx.Err(); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithTimeoutCannotWait(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), &stepClock{}, time.Hour)
+	defer cancel()
+	<-ctx.Done()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if err := context.Cause(ctx); err != ErrCannotWait {
+		t.Fatalf("Cause: got %v, want %v", err, ErrCannotWait)
+	}
+}
+
+func TestWithTimeoutManualCancel(t *testing.T) {
+	m := NewManual(manualEpoch)
+	ctx, cancel := WithTimeout(context.Background(), m, time.Hour)
+	m.BlockUntil(1)
+	cancel()
+	<-ctx.Done()
+	// the wait is abandoned, rather than left pending on the clock.
+	waitPending(t, m, 0)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package clock
+
+import "time"
+
+// timerfdWait reports false, as timerfds are only available on Linux.
+func timerfdWait(c Clock, d time.Time) (<-chan error, func(), bool) {
+	return nil, nil, false
+}
@@ -0,0 +1,65 @@
+package clock
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// from include/linux/posix-timers.h
+const (
+	cpuclockSched         = 2
+	cpuclockPerthreadMask = 4
+)
+
+// ProcessClock returns a Clock which measures the CPU time consumed by the
+// process pid, like Process does for the calling process. Reading the CPU
+// time of another process may require the same credentials as signalling it.
+func ProcessClock(pid int) (Clock, error) {
+	return cpuClock(int32(^pid)<<3 | cpuclockSched)
+}
+
+// ThreadClock returns a Clock which measures the CPU time consumed by the
+// thread tid. The thread must belong to the calling process.
+func ThreadClock(tid int) (Clock, error) {
+	return cpuClock(int32(^tid)<<3 | cpuclockPerthreadMask | cpuclockSched)
+}
+
+// cpuClock returns a clock for id, the equivalent of clock_getcpuclockid(3)
+// and pthread_getcpuclockid(3), after checking that id exists.
+func cpuClock(id int32) (Clock, error) {
+	c := &clock{uintptr(id)}
+	var ts syscall.Timespec
+	_, _, e := syscall.Syscall(syscall.SYS_CLOCK_GETRES, c.clockid, uintptr(unsafe.Pointer(&ts)), 0)
+	if e != 0 {
+		return nil, os.NewSyscallError("clock_getres", e)
+	}
+	return c, nil
+}
+
+// cpuClockName returns the name of a CPU-time clock returned by ProcessClock
+// or ThreadClock.
+func cpuClockName(clockid uintptr) (string, bool) {
+	id := int32(clockid)
+	if id >= 0 {
+		return "", false
+	}
+	if id&cpuclockPerthreadMask != 0 {
+		return fmt.Sprintf("thread(%d)", ^(id >> 3)), true
+	}
+	return fmt.Sprintf("process(%d)", ^(id >> 3)), true
+}
+
+// ThreadTime runs f on a locked operating system thread and returns the CPU
+// time that thread spent running f, as measured by Thread. Goroutines started
+// by f are not included.
+func ThreadTime(f func()) time.Duration {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	start := Thread.Now()
+	f()
+	return Thread.Now().Sub(start)
+}
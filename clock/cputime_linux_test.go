@@ -0,0 +1,74 @@
+package clock
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func spin(d time.Duration) {
+	for start := Thread.Now(); Thread.Now().Sub(start) < d; {
+	}
+}
+
+func TestProcessClockSelf(t *testing.T) {
+	c, err := ProcessClock(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if now := c.Now(); now.IsZero() || now == time.Unix(0, 0) {
+		t.Fatalf("got %v, expecting non zero", now)
+	}
+	if got, want := fmt.Sprint(c), fmt.Sprintf("process(%d)", os.Getpid()); got != want {
+		t.Fatalf("String: got %q, want %q", got, want)
+	}
+}
+
+func TestProcessClockChild(t *testing.T) {
+	cmd := exec.Command("sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	c, err := ProcessClock(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.(CheckedClock).NowErr(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessClockMissing(t *testing.T) {
+	if _, err := ProcessClock(1 << 26); err == nil {
+		t.Fatal("expected error for non existent pid")
+	}
+}
+
+func TestThreadClock(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	c, err := ThreadClock(syscall.Gettid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := c.Now()
+	spin(10 * time.Millisecond)
+	if d := c.Now().Sub(start); d < 10*time.Millisecond {
+		t.Fatalf("thread clock advanced %v, expected at least 10ms", d)
+	}
+}
+
+func TestThreadTime(t *testing.T) {
+	if d := ThreadTime(func() { spin(10 * time.Millisecond) }); d < 10*time.Millisecond {
+		t.Fatalf("got %v, expected at least 10ms", d)
+	}
+	if d := ThreadTime(func() { time.Sleep(50 * time.Millisecond) }); d > 25*time.Millisecond {
+		t.Fatalf("got %v while sleeping, expected little CPU time", d)
+	}
+}
@@ -8,7 +8,6 @@ import (
 )
 
 func main() {
-	rt, pt := clock.Realtime.Now(), clock.Process.Now()
-	time.Sleep(time.Second)
-	fmt.Printf("Wall clock time: %v, process CPU time: %v\n", clock.Realtime.Now().Sub(rt), clock.Process.Now().Sub(pt))
+	m := clock.Measure(func() { time.Sleep(time.Second) })
+	fmt.Printf("Wall clock time: %v, process CPU time: %v\n", m.Wall, m.Process)
 }
@@ -0,0 +1,255 @@
+// Package histogram records latency distributions measured on a clock.Clock.
+//
+// A Histogram uses log-linear buckets, in the style of HdrHistogram, which
+// cover every positive time.Duration with a relative error below 1%, in a
+// fixed amount of memory. Record it with Monotonic for wall latency, or
+// with Process or a thread clock for the CPU cost of an operation.
+package histogram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+
+	"github.com/davecheney/junk/clock"
+)
+
+const (
+	subBits    = 8                                 // bits of precision per bucket
+	subCount   = 1 << subBits                      // values below subCount have their own bucket
+	halfCount  = subCount / 2                      // buckets per power of two above subCount
+	numBuckets = subCount + (63-subBits)*halfCount // enough for math.MaxInt64
+)
+
+// index returns the bucket which holds v.
+func index(v uint64) int {
+	if v < subCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - subBits
+	sub := v >> uint(shift)
+	return subCount + (shift-1)*halfCount + int(sub-halfCount)
+}
+
+// bounds returns the lowest and highest values held by bucket i.
+func bounds(i int) (lo, hi uint64) {
+	if i < subCount {
+		return uint64(i), uint64(i)
+	}
+	j := i - subCount
+	shift := uint(j/halfCount + 1)
+	lo = uint64(j%halfCount+halfCount) << shift
+	return lo, lo + 1<<shift - 1
+}
+
+// Histogram records durations measured on a Clock. It is safe for
+// concurrent use.
+type Histogram struct {
+	clock  clock.Clock
+	counts [numBuckets]uint64
+	sum    uint64
+}
+
+// New returns an empty Histogram which measures samples on c.
+func New(c clock.Clock) *Histogram {
+	return &Histogram{clock: c}
+}
+
+// Clock returns the Clock the Histogram measures samples on.
+func (h *Histogram) Clock() clock.Clock { return h.clock }
+
+// Record records d. Negative durations are recorded as zero.
+func (h *Histogram) Record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.AddUint64(&h.counts[index(uint64(d))], 1)
+	atomic.AddUint64(&h.sum, uint64(d))
+}
+
+// Since records the time elapsed on the Histogram's Clock since start,
+// which must have been read from the same Clock.
+func (h *Histogram) Since(start time.Time) {
+	h.Record(h.clock.Now().Sub(start))
+}
+
+// Time runs f and records how long it took on the Histogram's Clock.
+func (h *Histogram) Time(f func()) {
+	start := h.clock.Now()
+	f()
+	h.Since(start)
+}
+
+// Snapshot returns a copy of the samples recorded so far.
+func (h *Histogram) Snapshot() *Snapshot {
+	s := new(Snapshot)
+	for i := range h.counts {
+		s.counts[i] = atomic.LoadUint64(&h.counts[i])
+		s.count += s.counts[i]
+	}
+	s.sum = atomic.LoadUint64(&h.sum)
+	return s
+}
+
+// Reset discards all recorded samples.
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		atomic.StoreUint64(&h.counts[i], 0)
+	}
+	atomic.StoreUint64(&h.sum, 0)
+}
+
+// A Snapshot is an immutable copy of a Histogram's samples. Snapshots from
+// different Histograms can be combined with Merge.
+type Snapshot struct {
+	counts [numBuckets]uint64
+	count  uint64
+	sum    uint64
+}
+
+// Merge adds the samples in o to s.
+func (s *Snapshot) Merge(o *Snapshot) {
+	for i, n := range o.counts {
+		s.counts[i] += n
+	}
+	s.count += o.count
+	s.sum += o.sum
+}
+
+// Count returns the number of samples.
+func (s *Snapshot) Count() uint64 { return s.count }
+
+// Mean returns the mean of the samples, or zero if there are none.
+func (s *Snapshot) Mean() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return time.Duration(s.sum / s.count)
+}
+
+// Min returns the smallest sample, to within the Histogram's precision.
+func (s *Snapshot) Min() time.Duration {
+	for i, n := range s.counts {
+		if n > 0 {
+			lo, _ := bounds(i)
+			return time.Duration(lo)
+		}
+	}
+	return 0
+}
+
+// Max returns the largest sample, to within the Histogram's precision.
+func (s *Snapshot) Max() time.Duration {
+	for i := len(s.counts) - 1; i >= 0; i-- {
+		if s.counts[i] > 0 {
+			_, hi := bounds(i)
+			return time.Duration(hi)
+		}
+	}
+	return 0
+}
+
+// Percentile returns the smallest duration which at least p percent of the
+// samples are less than or equal to, to within the Histogram's precision.
+// p is clamped to [0, 100].
+func (s *Snapshot) Percentile(p float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	p = math.Max(0, math.Min(100, p))
+	rank := uint64(math.Ceil(p / 100 * float64(s.count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range s.counts {
+		seen += n
+		if seen >= rank {
+			_, hi := bounds(i)
+			return time.Duration(hi)
+		}
+	}
+	return s.Max()
+}
+
+// percentiles are reported by WriteTo and MarshalJSON.
+var percentiles = []float64{50, 90, 99, 99.9, 99.99, 100}
+
+// WriteTo writes a text summary of s to w.
+func (s *Snapshot) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	printf := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(w, format, args...)
+		total += int64(n)
+		return err
+	}
+	if err := printf("count\t%d\nmin\t%v\nmean\t%v\nmax\t%v\n", s.count, s.Min(), s.Mean(), s.Max()); err != nil {
+		return total, err
+	}
+	for _, p := range percentiles {
+		if err := printf("p%v\t%v\n", p, s.Percentile(p)); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+type jsonBucket struct {
+	Max   uint64 `json:"max_ns"`
+	Count uint64 `json:"count"`
+}
+
+type jsonSnapshot struct {
+	Count       uint64           `json:"count"`
+	Sum         uint64           `json:"sum_ns"`
+	Min         time.Duration    `json:"min_ns"`
+	Mean        time.Duration    `json:"mean_ns"`
+	Max         time.Duration    `json:"max_ns"`
+	Percentiles map[string]int64 `json:"percentiles_ns"`
+	Buckets     []jsonBucket     `json:"buckets"`
+}
+
+// MarshalJSON encodes a summary of s, and its non empty buckets so the
+// Snapshot can be decoded and merged elsewhere.
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	j := jsonSnapshot{
+		Count:       s.count,
+		Sum:         s.sum,
+		Min:         s.Min(),
+		Mean:        s.Mean(),
+		Max:         s.Max(),
+		Percentiles: make(map[string]int64),
+		Buckets:     []jsonBucket{},
+	}
+	for _, p := range percentiles {
+		j.Percentiles[fmt.Sprint(p)] = int64(s.Percentile(p))
+	}
+	for i, n := range s.counts {
+		if n > 0 {
+			_, hi := bounds(i)
+			j.Buckets = append(j.Buckets, jsonBucket{Max: hi, Count: n})
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON decodes a Snapshot encoded by MarshalJSON.
+func (s *Snapshot) UnmarshalJSON(b []byte) error {
+	var j jsonSnapshot
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*s = Snapshot{sum: j.Sum}
+	for _, b := range j.Buckets {
+		if b.Max > math.MaxInt64 {
+			return fmt.Errorf("histogram: bucket %d out of range", b.Max)
+		}
+		s.counts[index(b.Max)] += b.Count
+		s.count += b.Count
+	}
+	return nil
+}
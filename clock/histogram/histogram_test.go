@@ -0,0 +1,127 @@
+package histogram
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davecheney/junk/clock"
+)
+
+func TestBuckets(t *testing.T) {
+	for _, v := range []uint64{0, 1, subCount - 1, subCount, subCount + 1, 1000, 123456789, 1 << 40, math.MaxInt64} {
+		i := index(v)
+		if i < 0 || i >= numBuckets {
+			t.Fatalf("index(%d) = %d, out of range", v, i)
+		}
+		lo, hi := bounds(i)
+		if v < lo || v > hi {
+			t.Fatalf("value %d in bucket %d with bounds [%d, %d]", v, i, lo, hi)
+		}
+		if err := float64(hi-lo) / float64(lo+1); err > 0.01 {
+			t.Fatalf("bucket %d [%d, %d]: relative error %v", i, lo, hi, err)
+		}
+	}
+	for i := 1; i < numBuckets; i++ {
+		_, prev := bounds(i - 1)
+		if lo, _ := bounds(i); lo != prev+1 {
+			t.Fatalf("bucket %d starts at %d, previous ended at %d", i, lo, prev)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	h := New(clock.Monotonic)
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	s := h.Snapshot()
+	if s.Count() != 1000 {
+		t.Fatalf("Count: got %d, want 1000", s.Count())
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Microsecond},
+		{50, 500 * time.Microsecond},
+		{99, 990 * time.Microsecond},
+		{100, time.Millisecond},
+	}
+	for _, tt := range tests {
+		got := s.Percentile(tt.p)
+		if d := math.Abs(float64(got-tt.want)) / float64(tt.want); d > 0.01 {
+			t.Fatalf("Percentile(%v): got %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if m := s.Mean(); m != 500500*time.Nanosecond {
+		t.Fatalf("Mean: got %v, want 500.5µs", m)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := New(clock.Monotonic), New(clock.Monotonic)
+	a.Record(time.Millisecond)
+	b.Record(time.Second)
+	s := a.Snapshot()
+	s.Merge(b.Snapshot())
+	if s.Count() != 2 {
+		t.Fatalf("Count: got %d, want 2", s.Count())
+	}
+	if s.Min() > time.Millisecond || s.Max() < time.Second {
+		t.Fatalf("got min %v max %v, want 1ms and 1s", s.Min(), s.Max())
+	}
+}
+
+func TestTime(t *testing.T) {
+	c := clock.NewManual(time.Unix(0, 0))
+	h := New(c)
+	h.Time(func() { c.Advance(3 * time.Millisecond) })
+	start := c.Now()
+	c.Advance(time.Second)
+	h.Since(start)
+	s := h.Snapshot()
+	if p50 := s.Percentile(50); s.Count() != 2 || p50 < 3*time.Millisecond || p50 > 3030*time.Microsecond || s.Max() < time.Second {
+		t.Fatalf("got count %d, p50 %v, max %v", s.Count(), s.Percentile(50), s.Max())
+	}
+	h.Reset()
+	if n := h.Snapshot().Count(); n != 0 {
+		t.Fatalf("Count after Reset: got %d, want 0", n)
+	}
+}
+
+func TestExport(t *testing.T) {
+	h := New(clock.Monotonic)
+	for i := 0; i < 100; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	s := h.Snapshot()
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "p99\t") {
+		t.Fatalf("text export missing p99:\n%s", buf.String())
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Snapshot
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count() != s.Count() || got.Percentile(90) != s.Percentile(90) || got.Mean() != s.Mean() {
+		t.Fatalf("JSON round trip: got %s", b)
+	}
+}
+
+func BenchmarkRecord(b *testing.B) {
+	h := New(clock.Monotonic)
+	for i := 0; i < b.N; i++ {
+		h.Record(time.Duration(i))
+	}
+}
@@ -0,0 +1,95 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrClockMismatch is returned when comparing Instants read from different clocks.
+var ErrClockMismatch = errors.New("clock: instants are from different clocks")
+
+// An Instant is a reading of one of the kernel clocks provided by this
+// package. Unlike the time.Time returned by Now, an Instant remembers which
+// clock it was read from, so readings of Monotonic, Process or Boottime are
+// not mistaken for, or compared against, wall clock times.
+type Instant struct {
+	c  clock // the clock which was read
+	ns int64 // nanoseconds since the clock's unspecified origin
+}
+
+// Read returns the current Instant of c, which must be one of the Clocks
+// provided by this package.
+func Read(c Clock) (Instant, error) {
+	k, ok := c.(*clock)
+	if !ok {
+		return Instant{}, ErrNotKernelClock
+	}
+	ts, err := gettime(k.clockid)
+	if err != nil {
+		return Instant{}, err
+	}
+	return Instant{c: *k, ns: ts.Nano()}, nil
+}
+
+// Clock returns the Clock this Instant was read from.
+func (i Instant) Clock() Clock {
+	if i.c.clockid < uintptr(len(clocks)) {
+		return clocks[i.c.clockid].Clock
+	}
+	c := i.c
+	return &c
+}
+
+// Duration returns the time between the clock's origin and i.
+func (i Instant) Duration() time.Duration { return time.Duration(i.ns) }
+
+// Add returns the Instant i+d on the same clock.
+func (i Instant) Add(d time.Duration) Instant {
+	i.ns += int64(d)
+	return i
+}
+
+// Sub returns the duration i-j. It returns ErrClockMismatch if i and j were
+// read from different clocks.
+func (i Instant) Sub(j Instant) (time.Duration, error) {
+	if i.c != j.c {
+		return 0, ErrClockMismatch
+	}
+	return time.Duration(i.ns - j.ns), nil
+}
+
+// Since returns the time elapsed on i's clock since i.
+func (i Instant) Since() time.Duration {
+	now, err := Read(&i.c)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(now.ns - i.ns)
+}
+
+// Before reports whether i is before j. It panics if i and j were read
+// from different clocks.
+func (i Instant) Before(j Instant) bool {
+	i.mustMatch(j)
+	return i.ns < j.ns
+}
+
+// After reports whether i is after j. It panics if i and j were read from
+// different clocks.
+func (i Instant) After(j Instant) bool {
+	i.mustMatch(j)
+	return i.ns > j.ns
+}
+
+// String returns the Instant as the clock's name and the duration since
+// its origin, eg. "monotonic+72h3m0.5s".
+func (i Instant) String() string {
+	return fmt.Sprintf("%v+%v", &i.c, time.Duration(i.ns))
+}
+
+func (i Instant) mustMatch(j Instant) {
+	if i.c != j.c {
+		panic(fmt.Sprintf("clock: comparing %v with %v", i, j))
+	}
+}
@@ -0,0 +1,62 @@
+package clock
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func read(t *testing.T, c Clock) Instant {
+	i, err := Read(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i
+}
+
+func TestInstant(t *testing.T) {
+	a := read(t, Monotonic)
+	time.Sleep(time.Millisecond)
+	b := read(t, Monotonic)
+	if !a.Before(b) || b.Before(a) || !b.After(a) {
+		t.Fatalf("expected %v before %v", a, b)
+	}
+	d, err := b.Sub(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d < time.Millisecond {
+		t.Fatalf("Sub: got %v, expected at least 1ms", d)
+	}
+	if got, _ := a.Add(d).Sub(b); got != 0 {
+		t.Fatalf("Add: got %v from b, want 0", got)
+	}
+	if s := a.Since(); s < d {
+		t.Fatalf("Since: got %v, expected at least %v", s, d)
+	}
+	if a.Clock() != Monotonic {
+		t.Fatalf("Clock: got %v, want %v", a.Clock(), Monotonic)
+	}
+	if s := a.String(); !strings.HasPrefix(s, "monotonic+") {
+		t.Fatalf("String: got %q", s)
+	}
+}
+
+func TestInstantMismatch(t *testing.T) {
+	m, b := read(t, Monotonic), read(t, Boottime)
+	if _, err := m.Sub(b); err != ErrClockMismatch {
+		t.Fatalf("Sub: got %v, want %v", err, ErrClockMismatch)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Before: expected panic")
+		}
+	}()
+	m.Before(b)
+}
+
+func TestReadNotKernelClock(t *testing.T) {
+	if _, err := Read(NewManual(manualEpoch)); err != ErrNotKernelClock {
+		t.Fatalf("got %v, want %v", err, ErrNotKernelClock)
+	}
+}
@@ -0,0 +1,168 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Manual is a Clock whose time only changes when Set or Advance are called.
+// Sleeps and timers scheduled against a Manual clock fire only when the
+// clock is moved to, or past, their deadline, which makes code that
+// depends on the passage of time deterministic to test.
+type Manual struct {
+	mu      sync.Mutex
+	cond    *sync.Cond // signalled when waiters changes
+	now     time.Time
+	waiters []*waiter // sorted by deadline
+}
+
+// A waiter is a pending sleep or timer on a Manual clock.
+type waiter struct {
+	deadline time.Time
+	c        chan time.Time // receives deadline, if non nil
+	f        func()         // called in its own goroutine, if non nil
+}
+
+// NewManual returns a Manual clock set to t.
+func NewManual(t time.Time) *Manual {
+	m := &Manual{now: t}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now returns the current time of the Manual clock.
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// Set sets the clock to t, firing any sleeps or timers whose deadline is
+// at or before t. Sleeps and channels are woken in deadline order, but as
+// each AfterFunc call runs in its own goroutine, those calls may run in any
+// order. Setting the clock backwards does not fire anything.
+func (m *Manual) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(t)
+}
+
+// Advance moves the clock forward by d.
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(m.now.Add(d))
+}
+
+// setLocked is Set for a caller which holds m.mu.
+func (m *Manual) setLocked(t time.Time) {
+	m.now = t
+	var n int
+	for _, w := range m.waiters {
+		if w.deadline.After(t) {
+			break
+		}
+		w.fire()
+		n++
+	}
+	if n > 0 {
+		m.waiters = m.waiters[n:]
+		m.cond.Broadcast()
+	}
+}
+
+// Sleep blocks until the clock has been advanced by at least d.
+func (m *Manual) Sleep(d time.Duration) {
+	<-m.After(d)
+}
+
+// SleepUntil blocks until the clock has been set to t or later.
+func (m *Manual) SleepUntil(t time.Time) error {
+	c := make(chan time.Time, 1)
+	m.add(&waiter{c: c, deadline: t})
+	<-c
+	return nil
+}
+
+// After returns a channel which receives the deadline once the clock has
+// been advanced by at least d.
+func (m *Manual) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	m.add(&waiter{c: c, deadline: m.Now().Add(d)})
+	return c
+}
+
+// AfterFunc calls f in its own goroutine once the clock has been advanced
+// by at least d. The returned ManualTimer can be used to cancel the call.
+func (m *Manual) AfterFunc(d time.Duration, f func()) *ManualTimer {
+	return m.afterFuncAt(m.Now().Add(d), f)
+}
+
+// afterFuncAt is AfterFunc with an absolute deadline.
+func (m *Manual) afterFuncAt(t time.Time, f func()) *ManualTimer {
+	w := &waiter{f: f, deadline: t}
+	m.add(w)
+	return &ManualTimer{m: m, w: w}
+}
+
+// BlockUntil blocks until at least n sleeps or timers are pending on the
+// clock. Tests use it to wait for the code under test to reach a sleep
+// before advancing the clock.
+func (m *Manual) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for len(m.waiters) < n {
+		m.cond.Wait()
+	}
+}
+
+func (m *Manual) add(w *waiter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !w.deadline.After(m.now) {
+		w.fire()
+		return
+	}
+	i := sort.Search(len(m.waiters), func(i int) bool {
+		return m.waiters[i].deadline.After(w.deadline)
+	})
+	m.waiters = append(m.waiters, nil)
+	copy(m.waiters[i+1:], m.waiters[i:])
+	m.waiters[i] = w
+	m.cond.Broadcast()
+}
+
+func (m *Manual) remove(w *waiter) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.waiters {
+		if m.waiters[i] == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			m.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+func (w *waiter) fire() {
+	if w.c != nil {
+		w.c <- w.deadline
+	}
+	if w.f != nil {
+		go w.f()
+	}
+}
+
+// A ManualTimer is a pending call scheduled by Manual.AfterFunc.
+type ManualTimer struct {
+	m *Manual
+	w *waiter
+}
+
+// Stop prevents the timer from firing. It returns false if the timer has
+// already fired or been stopped.
+func (t *ManualTimer) Stop() bool {
+	return t.m.remove(t.w)
+}
@@ -0,0 +1,111 @@
+package clock
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+var manualEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestManualAdvance(t *testing.T) {
+	m := NewManual(manualEpoch)
+	if now := m.Now(); !now.Equal(manualEpoch) {
+		t.Fatalf("Now: got %v, want %v", now, manualEpoch)
+	}
+	m.Advance(time.Hour)
+	if now, want := m.Now(), manualEpoch.Add(time.Hour); !now.Equal(want) {
+		t.Fatalf("Now: got %v, want %v", now, want)
+	}
+}
+
+func TestManualAdvanceConcurrent(t *testing.T) {
+	m := NewManual(manualEpoch)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Advance(time.Second)
+		}()
+	}
+	wg.Wait()
+	if now, want := m.Now(), manualEpoch.Add(100*time.Second); !now.Equal(want) {
+		t.Fatalf("Now: got %v, want %v", now, want)
+	}
+}
+
+func TestManualAfter(t *testing.T) {
+	m := NewManual(manualEpoch)
+	c := m.After(time.Second)
+	m.Advance(999 * time.Millisecond)
+	select {
+	case <-c:
+		t.Fatal("After fired early")
+	default:
+	}
+	m.Advance(time.Millisecond)
+	select {
+	case got := <-c:
+		if want := manualEpoch.Add(time.Second); !got.Equal(want) {
+			t.Fatalf("After: got %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("After did not fire")
+	}
+}
+
+func TestManualSleep(t *testing.T) {
+	m := NewManual(manualEpoch)
+	done := make(chan struct{})
+	go func() {
+		m.Sleep(time.Minute)
+		close(done)
+	}()
+	m.BlockUntil(1)
+	m.Set(manualEpoch.Add(time.Minute))
+	<-done
+}
+
+func TestManualAfterFunc(t *testing.T) {
+	m := NewManual(manualEpoch)
+	fired := make(chan int, 2)
+	m.AfterFunc(2*time.Second, func() { fired <- 2 })
+	m.AfterFunc(time.Second, func() { fired <- 1 })
+	t3 := m.AfterFunc(3*time.Second, func() { fired <- 3 })
+	if !t3.Stop() {
+		t.Fatal("Stop: expected true for pending timer")
+	}
+	if t3.Stop() {
+		t.Fatal("Stop: expected false for stopped timer")
+	}
+	m.Advance(time.Hour)
+	got := map[int]bool{<-fired: true, <-fired: true}
+	if !got[1] || !got[2] {
+		t.Fatalf("got %v, expected timers 1 and 2 to fire", got)
+	}
+	select {
+	case n := <-fired:
+		t.Fatalf("timer %d fired after Stop", n)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+// waitPending waits for the number of sleeps and timers pending on m to
+// become n, or fails t after a second.
+func waitPending(t *testing.T, m *Manual, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		m.mu.Lock()
+		got := len(m.waiters)
+		m.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pending: got %d, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,85 @@
+package clock
+
+import (
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// from /usr/include/linux/resource.h
+const (
+	RUSAGE_SELF     = 0
+	RUSAGE_CHILDREN = -1
+	RUSAGE_THREAD   = 1
+)
+
+// Rusage is a summary of the resources reported by getrusage(2).
+type Rusage struct {
+	User                time.Duration // CPU time spent in user mode
+	System              time.Duration // CPU time spent in the kernel
+	MaxRSS              int64         // maximum resident set size, in bytes
+	MinorFaults         int64         // page faults serviced without I/O
+	MajorFaults         int64         // page faults which required I/O
+	VoluntarySwitches   int64         // context switches while waiting for a resource
+	InvoluntarySwitches int64         // context switches due to preemption
+}
+
+// Measurement is the cost of running a function, as returned by Measure.
+type Measurement struct {
+	Wall    time.Duration // elapsed Monotonic time
+	Process time.Duration // CPU time consumed by the whole process
+	Thread  time.Duration // CPU time consumed by the thread which ran the function
+
+	// Self and Children are the change in getrusage(2) for this process, and
+	// for any children waited for, while the function ran. MaxRSS is a high
+	// water mark, so it is reported as of when the function returned.
+	Self, Children Rusage
+}
+
+// Measure runs f on a locked operating system thread and reports the wall
+// time, CPU time and resources used while it ran. Process and Self include
+// work done by other goroutines; Thread does not include goroutines started
+// by f.
+func Measure(f func()) Measurement {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	self0, child0 := getrusage(RUSAGE_SELF), getrusage(RUSAGE_CHILDREN)
+	w0, p0, t0 := Monotonic.Now(), Process.Now(), Thread.Now()
+	f()
+	w1, p1, t1 := Monotonic.Now(), Process.Now(), Thread.Now()
+	self1, child1 := getrusage(RUSAGE_SELF), getrusage(RUSAGE_CHILDREN)
+	return Measurement{
+		Wall:     w1.Sub(w0),
+		Process:  p1.Sub(p0),
+		Thread:   t1.Sub(t0),
+		Self:     self1.sub(self0),
+		Children: child1.sub(child0),
+	}
+}
+
+func getrusage(who int) Rusage {
+	var ru syscall.Rusage
+	syscall.Getrusage(who, &ru)
+	return Rusage{
+		User:                time.Duration(ru.Utime.Nano()),
+		System:              time.Duration(ru.Stime.Nano()),
+		MaxRSS:              int64(ru.Maxrss) * 1024, // kilobytes
+		MinorFaults:         int64(ru.Minflt),
+		MajorFaults:         int64(ru.Majflt),
+		VoluntarySwitches:   int64(ru.Nvcsw),
+		InvoluntarySwitches: int64(ru.Nivcsw),
+	}
+}
+
+// sub returns the change in r since prev, except MaxRSS which is taken from r.
+func (r Rusage) sub(prev Rusage) Rusage {
+	return Rusage{
+		User:                r.User - prev.User,
+		System:              r.System - prev.System,
+		MaxRSS:              r.MaxRSS,
+		MinorFaults:         r.MinorFaults - prev.MinorFaults,
+		MajorFaults:         r.MajorFaults - prev.MajorFaults,
+		VoluntarySwitches:   r.VoluntarySwitches - prev.VoluntarySwitches,
+		InvoluntarySwitches: r.InvoluntarySwitches - prev.InvoluntarySwitches,
+	}
+}
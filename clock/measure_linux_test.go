@@ -0,0 +1,43 @@
+package clock
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	m := Measure(func() {
+		spin(20 * time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+	})
+	if m.Wall < 40*time.Millisecond {
+		t.Fatalf("Wall: got %v, expected at least 40ms", m.Wall)
+	}
+	if m.Thread < 20*time.Millisecond || m.Thread > m.Wall {
+		t.Fatalf("Thread: got %v, expected between 20ms and %v", m.Thread, m.Wall)
+	}
+	if m.Process < m.Thread {
+		t.Fatalf("Process: got %v, expected at least %v", m.Process, m.Thread)
+	}
+	if m.Self.User+m.Self.System == 0 {
+		t.Fatal("Self: expected non zero CPU time")
+	}
+	if m.Self.MaxRSS == 0 {
+		t.Fatal("Self: expected non zero MaxRSS")
+	}
+	t.Logf("%+v", m)
+}
+
+func TestMeasureChildren(t *testing.T) {
+	var err error
+	m := Measure(func() {
+		err = exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done").Run()
+	})
+	if err != nil {
+		t.Skip(err)
+	}
+	if m.Children.User+m.Children.System == 0 {
+		t.Fatalf("Children: expected non zero CPU time, got %+v", m.Children)
+	}
+}
@@ -0,0 +1,206 @@
+package clock
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// from /usr/include/linux/timex.h
+const (
+	TIME_OK    = 0
+	TIME_INS   = 1
+	TIME_DEL   = 2
+	TIME_OOP   = 3
+	TIME_WAIT  = 4
+	TIME_ERROR = 5
+
+	STA_UNSYNC = 0x0040
+	STA_NANO   = 0x2000
+)
+
+// SyncStatus is the kernel's view of NTP synchronisation, as reported by
+// adjtimex(2).
+type SyncStatus struct {
+	Synchronized bool          // clock is synchronised to an NTP source
+	State        int           // clock state, one of the TIME_ constants
+	Status       int           // STA_ status bits
+	Offset       time.Duration // time offset being corrected
+	Frequency    float64       // frequency offset, in ppm
+	MaxError     time.Duration // maximum error
+	EstError     time.Duration // estimated error
+}
+
+// NTPStatus returns the kernel's NTP synchronisation status. The clock is
+// only queried, never adjusted.
+func NTPStatus() (SyncStatus, error) {
+	var tx syscall.Timex // Modes == 0, read only
+	state, err := syscall.Adjtimex(&tx)
+	if err != nil {
+		return SyncStatus{}, os.NewSyscallError("adjtimex", err)
+	}
+	offset := time.Duration(tx.Offset) * time.Microsecond
+	if tx.Status&STA_NANO != 0 {
+		offset = time.Duration(tx.Offset)
+	}
+	return SyncStatus{
+		Synchronized: state != TIME_ERROR && tx.Status&STA_UNSYNC == 0,
+		State:        state,
+		Status:       int(tx.Status),
+		Offset:       offset,
+		Frequency:    float64(tx.Freq) / 65536,
+		MaxError:     time.Duration(tx.Maxerror) * time.Microsecond,
+		EstError:     time.Duration(tx.Esterror) * time.Microsecond,
+	}, nil
+}
+
+// EventKind describes how the wall clock moved relative to the reference clock.
+type EventKind int
+
+const (
+	// Step is a discontinuous jump of the wall clock within one sample interval.
+	Step EventKind = iota
+
+	// Slew is a gradual drift of the wall clock accumulated over many samples.
+	Slew
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Step:
+		return "step"
+	case Slew:
+		return "slew"
+	default:
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+}
+
+// An Event reports that the wall clock moved relative to the reference clock
+// by more than the Monitor's threshold.
+type Event struct {
+	Kind     EventKind
+	Time     time.Time     // wall clock time the movement was detected
+	Offset   time.Duration // how far the wall clock moved, positive is forward
+	Interval time.Duration // reference time over which Offset accumulated
+	Sync     SyncStatus    // NTP status when the movement was detected
+	SyncErr  error         // error reading Sync, if any
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%v: wall clock %v %v over %v (ntp synchronized: %v)", e.Time, e.Kind, e.Offset, e.Interval, e.Sync.Synchronized)
+}
+
+// Monitor samples Realtime against a reference clock and reports steps and
+// slews of the wall clock.
+type Monitor struct {
+	C <-chan Event // The channel on which events are delivered, closed once the Monitor stops.
+
+	wall, ref Clock
+	ticker    *Ticker
+	stop      chan struct{} // closed by Stop
+	done      chan struct{} // closed when run returns
+	once      sync.Once     // guards close(stop)
+}
+
+// NewMonitor returns a Monitor which samples Realtime against ref, usually
+// Monotonic or Boottime, every interval. A movement of the wall clock within
+// one interval of at least threshold is reported as a Step, smaller
+// movements accumulate until they reach threshold and are reported as a Slew.
+func NewMonitor(ref Clock, interval, threshold time.Duration) *Monitor {
+	c := make(chan Event, 16)
+	m := &Monitor{
+		C:      c,
+		wall:   Realtime,
+		ref:    ref,
+		ticker: NewTicker(ref, interval),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go m.run(c, newSkew(m.wall.Now(), ref.Now(), threshold))
+	return m
+}
+
+// Stop stops the Monitor, and closes C before it returns. Events already
+// buffered on C can still be received. Calling Stop more than once has no
+// effect.
+func (m *Monitor) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+		m.ticker.Stop()
+	})
+	<-m.done
+}
+
+// run samples the clocks until the Monitor is stopped or the ticker's
+// channel is closed, then closes c, and finally done.
+func (m *Monitor) run(c chan Event, s *skew) {
+	defer close(m.done)
+	defer close(c)
+	for {
+		select {
+		case <-m.stop:
+			return
+		case _, ok := <-m.ticker.C:
+			if !ok {
+				return
+			}
+		}
+		e, ok := s.sample(m.wall.Now(), m.ref.Now())
+		if !ok {
+			continue
+		}
+		e.Sync, e.SyncErr = NTPStatus()
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+		select {
+		case c <- e:
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// skew tracks the movement of a wall clock relative to a reference clock.
+type skew struct {
+	threshold time.Duration
+	w0, r0    time.Time     // previous sample
+	since     time.Time     // reference time drift started accumulating
+	drift     time.Duration // offset accumulated since the last event
+}
+
+func newSkew(w, r time.Time, threshold time.Duration) *skew {
+	return &skew{threshold: threshold, w0: w, r0: r, since: r}
+}
+
+// sample records a pair of readings of the wall and reference clocks and
+// returns an Event if the wall clock has moved by at least the threshold.
+func (s *skew) sample(w1, r1 time.Time) (Event, bool) {
+	d := w1.Sub(s.w0) - r1.Sub(s.r0)
+	e := Event{Time: w1}
+	switch {
+	case abs(d) >= s.threshold:
+		e.Kind, e.Offset, e.Interval = Step, d, r1.Sub(s.r0)
+	case abs(s.drift+d) >= s.threshold:
+		e.Kind, e.Offset, e.Interval = Slew, s.drift+d, r1.Sub(s.since)
+	default:
+		s.drift += d
+		s.w0, s.r0 = w1, r1
+		return e, false
+	}
+	s.drift, s.since = 0, r1
+	s.w0, s.r0 = w1, r1
+	return e, true
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,82 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNTPStatus(t *testing.T) {
+	s, err := NTPStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%+v", s)
+}
+
+func TestSkew(t *testing.T) {
+	type sample struct {
+		wall time.Duration // wall clock movement over one second of reference time
+		kind EventKind
+		ok   bool
+	}
+	tests := []struct {
+		name     string
+		samples  []sample
+		offset   time.Duration
+		interval time.Duration
+	}{{
+		name: "step",
+		samples: []sample{
+			{wall: time.Second},
+			{wall: time.Second - time.Minute, kind: Step, ok: true},
+		},
+		offset:   -time.Minute,
+		interval: time.Second,
+	}, {
+		name: "slew",
+		samples: []sample{
+			{wall: time.Second + 20*time.Millisecond},
+			{wall: time.Second + 20*time.Millisecond},
+			{wall: time.Second + 20*time.Millisecond},
+			{wall: time.Second + 20*time.Millisecond},
+			{wall: time.Second + 20*time.Millisecond, kind: Slew, ok: true},
+		},
+		offset:   100 * time.Millisecond,
+		interval: 5 * time.Second,
+	}, {
+		name: "slew backwards",
+		samples: []sample{
+			{wall: time.Second - 60*time.Millisecond},
+			{wall: time.Second - 60*time.Millisecond, kind: Slew, ok: true},
+		},
+		offset:   -120 * time.Millisecond,
+		interval: 2 * time.Second,
+	}}
+	for _, tt := range tests {
+		w, r := manualEpoch, manualEpoch
+		s := newSkew(w, r, 100*time.Millisecond)
+		for i, smp := range tt.samples {
+			w, r = w.Add(smp.wall), r.Add(time.Second)
+			e, ok := s.sample(w, r)
+			if ok != smp.ok {
+				t.Fatalf("%s: sample %d: got event %v, want %v", tt.name, i, ok, smp.ok)
+			}
+			if !ok {
+				continue
+			}
+			if e.Kind != smp.kind || e.Offset != tt.offset || e.Interval != tt.interval {
+				t.Fatalf("%s: got %v, want %v of %v over %v", tt.name, e, smp.kind, tt.offset, tt.interval)
+			}
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	m := NewMonitor(Monotonic, time.Millisecond, time.Hour)
+	time.Sleep(10 * time.Millisecond)
+	m.Stop()
+	m.Stop()
+	for e := range m.C {
+		t.Fatalf("unexpected event %v", e)
+	}
+}
@@ -0,0 +1,229 @@
+// Package rate provides a token bucket rate limiter driven by a clock.Clock.
+//
+// As the Clock is injected, a Limiter can run on clock.Monotonic in
+// production and on a clock.Manual in tests.
+package rate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/davecheney/junk/clock"
+)
+
+// ErrExceedsBurst is returned by Wait when more tokens are requested than
+// the Limiter's burst.
+var ErrExceedsBurst = errors.New("rate: request exceeds burst")
+
+// ErrDeadline is returned by Wait when the context's deadline would pass
+// before the tokens become available.
+var ErrDeadline = errors.New("rate: wait would exceed context deadline")
+
+// A Limiter is a token bucket which holds up to burst tokens and is refilled
+// with one token every interval. It is safe for concurrent use.
+type Limiter struct {
+	clock    clock.Clock
+	interval time.Duration
+	burst    int
+
+	mu        sync.Mutex // protects remaining fields
+	tokens    float64    // tokens available as of last, may be negative
+	last      time.Time  // when tokens was last updated
+	lastEvent time.Time  // latest time at which reserved tokens are available
+}
+
+// NewLimiter returns a full Limiter which allows burst events at once and
+// one further event every interval, as measured by c.
+func NewLimiter(c clock.Clock, interval time.Duration, burst int) *Limiter {
+	return &Limiter{
+		clock:    c,
+		interval: interval,
+		burst:    burst,
+		tokens:   float64(burst),
+		last:     c.Now(),
+	}
+}
+
+// Burst returns the maximum number of tokens the Limiter holds.
+func (l *Limiter) Burst() int { return l.burst }
+
+// Interval returns the time between tokens.
+func (l *Limiter) Interval() time.Duration { return l.interval }
+
+// SetBurst changes the maximum number of tokens the Limiter holds.
+func (l *Limiter) SetBurst(burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	l.burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+// Allow reports whether an event may happen now, and if so consumes a token.
+func (l *Limiter) Allow() bool { return l.AllowN(1) }
+
+// AllowN reports whether n events may happen now, and if so consumes n tokens.
+func (l *Limiter) AllowN(n int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.advance(l.clock.Now())
+	if l.tokens < float64(n) {
+		return false
+	}
+	l.tokens -= float64(n)
+	if l.last.After(l.lastEvent) {
+		l.lastEvent = l.last
+	}
+	return true
+}
+
+// A Reservation holds tokens which become available at a future time.
+type Reservation struct {
+	l      *Limiter
+	ok     bool
+	n      int
+	at     time.Time // when the tokens are available
+	cancel sync.Once
+}
+
+// OK reports whether the tokens could be reserved. A Reservation for more
+// tokens than the Limiter's burst is never OK.
+func (r *Reservation) OK() bool { return r.ok }
+
+// Delay returns how long the holder must wait, from now, before acting.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return 0
+	}
+	if d := r.at.Sub(r.l.clock.Now()); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Cancel returns the reserved tokens to the Limiter, as far as it can. Once
+// the tokens are available they are treated as used, and nothing is
+// returned. Tokens reserved after r have been counted against r's, so
+// those are not returned either.
+func (r *Reservation) Cancel() {
+	if !r.ok {
+		return
+	}
+	r.cancel.Do(func() {
+		l := r.l
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		now := l.clock.Now()
+		if !now.Before(r.at) {
+			return
+		}
+		restore := float64(r.n) - l.tokensFor(l.lastEvent.Sub(r.at))
+		if restore <= 0 {
+			return
+		}
+		l.advance(now)
+		l.tokens += restore
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+		if r.at.Equal(l.lastEvent) {
+			// r was the latest reservation, so the one before it is.
+			prev := r.at.Add(-time.Duration(float64(r.n) * float64(l.interval)))
+			if !prev.Before(now) {
+				l.lastEvent = prev
+			}
+		}
+	})
+}
+
+// Reserve reserves a token, see ReserveN.
+func (l *Limiter) Reserve() *Reservation { return l.ReserveN(1) }
+
+// ReserveN reserves n tokens, which may not be available until some time in
+// the future. The caller should wait for r.Delay before acting, or call
+// r.Cancel if it decides not to.
+func (l *Limiter) ReserveN(n int) *Reservation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n > l.burst {
+		return &Reservation{l: l}
+	}
+	now := l.clock.Now()
+	l.advance(now)
+	l.tokens -= float64(n)
+	r := &Reservation{l: l, ok: true, n: n, at: now}
+	if l.tokens < 0 {
+		r.at = now.Add(time.Duration(-l.tokens * float64(l.interval)))
+	}
+	if r.at.After(l.lastEvent) {
+		l.lastEvent = r.at
+	}
+	return r
+}
+
+// Wait blocks until a token is available, see WaitN.
+func (l *Limiter) Wait(ctx context.Context) error { return l.WaitN(ctx, 1) }
+
+// WaitN blocks until n tokens are available, or ctx is done. If ctx has a
+// deadline which would pass before the tokens are available, WaitN returns
+// ErrDeadline immediately. The deadline is measured on the runtime's clock
+// and the delay on the Limiter's, so this check assumes the two run at the
+// same rate, as Monotonic does, and is only a guess for other Clocks.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := l.ReserveN(n)
+	if !r.OK() {
+		return ErrExceedsBurst
+	}
+	d := r.Delay()
+	if d == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		r.Cancel()
+		return ErrDeadline
+	}
+	wctx, cancel := clock.WithDeadline(ctx, l.clock, r.at)
+	defer cancel()
+	<-wctx.Done()
+	if err := ctx.Err(); err != nil {
+		r.Cancel()
+		return err
+	}
+	if err := context.Cause(wctx); err != context.DeadlineExceeded {
+		r.Cancel()
+		return err
+	}
+	return nil
+}
+
+// tokensFor returns the number of tokens accumulated over d.
+func (l *Limiter) tokensFor(d time.Duration) float64 {
+	if l.interval <= 0 {
+		return float64(l.burst)
+	}
+	return float64(d) / float64(l.interval)
+}
+
+// advance adds the tokens accumulated between last and now.
+func (l *Limiter) advance(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	if l.interval <= 0 {
+		l.tokens = float64(l.burst)
+		return
+	}
+	l.tokens += l.tokensFor(elapsed)
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+}
@@ -0,0 +1,149 @@
+package rate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/davecheney/junk/clock"
+)
+
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestAllow(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, time.Second, 3)
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("Allow %d: expected burst to be allowed", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("Allow: expected empty bucket to refuse")
+	}
+	c.Advance(999 * time.Millisecond)
+	if l.Allow() {
+		t.Fatal("Allow: token granted early")
+	}
+	c.Advance(time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("Allow: expected token after one interval")
+	}
+	c.Advance(time.Hour)
+	if l.AllowN(4) {
+		t.Fatal("AllowN: granted more than burst")
+	}
+	if !l.AllowN(3) {
+		t.Fatal("AllowN: expected bucket to refill to burst")
+	}
+}
+
+func TestReserve(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, 100*time.Millisecond, 1)
+	if d := l.Reserve().Delay(); d != 0 {
+		t.Fatalf("first reservation: got delay %v, want 0", d)
+	}
+	if d := l.Reserve().Delay(); d != 100*time.Millisecond {
+		t.Fatalf("second reservation: got delay %v, want 100ms", d)
+	}
+	r := l.Reserve()
+	if d := r.Delay(); d != 200*time.Millisecond {
+		t.Fatalf("third reservation: got delay %v, want 200ms", d)
+	}
+	r.Cancel()
+	r.Cancel() // idempotent
+	if d := l.Reserve().Delay(); d != 200*time.Millisecond {
+		t.Fatalf("after Cancel: got delay %v, want 200ms", d)
+	}
+	if l.ReserveN(2).OK() {
+		t.Fatal("ReserveN: expected reservation over burst to fail")
+	}
+}
+
+func TestReserveCancelAfterUse(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, time.Second, 2)
+	l.ReserveN(2)
+	r := l.Reserve()
+	c.Advance(time.Second)
+	// r's token is available, so it has been used.
+	r.Cancel()
+	if l.Allow() {
+		t.Fatal("Allow: Cancel after use returned the token")
+	}
+}
+
+func TestReserveCancelEarlier(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, time.Second, 1)
+	l.Allow()
+	r := l.Reserve()
+	if d := l.Reserve().Delay(); d != 2*time.Second {
+		t.Fatalf("got delay %v, want 2s", d)
+	}
+	// the later reservation was counted against r's token.
+	r.Cancel()
+	if d := l.Reserve().Delay(); d != 3*time.Second {
+		t.Fatalf("after Cancel: got delay %v, want 3s", d)
+	}
+}
+
+func TestWait(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, time.Second, 1)
+	ctx := context.Background()
+	if err := l.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	c.BlockUntil(1)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned %v before the clock advanced", err)
+	default:
+	}
+	c.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := l.WaitN(ctx, 2); err != ErrExceedsBurst {
+		t.Fatalf("WaitN: got %v, want %v", err, ErrExceedsBurst)
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	c := clock.NewManual(epoch)
+	l := NewLimiter(c, time.Hour, 1)
+	l.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := l.Wait(ctx); err != ErrDeadline {
+		t.Fatalf("got %v, want %v", err, ErrDeadline)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx) }()
+	c.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if d := l.Reserve().Delay(); d != time.Hour {
+		t.Fatalf("cancelled Wait did not return its token, got delay %v", d)
+	}
+}
+
+func TestWaitMonotonic(t *testing.T) {
+	l := NewLimiter(clock.Monotonic, 5*time.Millisecond, 1)
+	start := clock.Monotonic.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := clock.Monotonic.Now().Sub(start); d < 10*time.Millisecond {
+		t.Fatalf("3 waits took %v, expected at least 10ms", d)
+	}
+}
@@ -0,0 +1,162 @@
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCannotWait is returned when sleeping on a Clock that is not a Waiter.
+var ErrCannotWait = errors.New("clock: clock does not support waiting")
+
+// SleepUntil blocks until c reads t or later.
+func SleepUntil(c Clock, t time.Time) error {
+	w, ok := c.(Waiter)
+	if !ok {
+		return ErrCannotWait
+	}
+	return w.SleepUntil(t)
+}
+
+// Sleep blocks for at least d, as measured by c.
+func Sleep(c Clock, d time.Duration) error {
+	return SleepUntil(c, c.Now().Add(d))
+}
+
+// now reads c, reporting an error if c is a CheckedClock which could not
+// be read.
+func now(c Clock) (time.Time, error) {
+	if cc, ok := c.(CheckedClock); ok {
+		return cc.NowErr()
+	}
+	return c.Now(), nil
+}
+
+// An afterFuncer is a Clock which can call f once it reads t or later,
+// without a goroutine waiting for it. Manual is an afterFuncer.
+type afterFuncer interface {
+	Clock
+	afterFuncAt(t time.Time, f func()) *ManualTimer
+}
+
+// After waits for d to elapse on c, then sends the time read from c on the
+// returned channel. If c cannot wait, the channel is closed without a value.
+// Like time.After, the wait cannot be cancelled: on a Manual clock it stays
+// pending until the clock reaches it, and on other clocks a goroutine sleeps
+// until the deadline. Use WithTimeout for a wait which may be abandoned.
+func After(c Clock, d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	deadline := c.Now().Add(d)
+	if af, ok := c.(afterFuncer); ok {
+		af.afterFuncAt(deadline, func() { ch <- af.Now() })
+		return ch
+	}
+	go func() {
+		if err := SleepUntil(c, deadline); err != nil {
+			close(ch)
+			return
+		}
+		ch <- c.Now()
+	}()
+	return ch
+}
+
+// errStopped is returned by Ticker.sleepUntil once the Ticker is stopped.
+var errStopped = errors.New("clock: ticker stopped")
+
+// A Ticker delivers ticks of a Clock at intervals.
+type Ticker struct {
+	C    <-chan time.Time // The channel on which the ticks are delivered.
+	stop chan struct{}    // closed by Stop
+
+	mu      sync.Mutex // protects stopped
+	stopped bool
+}
+
+// NewTicker returns a Ticker which sends the time read from c on its channel
+// every d. Deadlines are absolute, so ticks do not drift, and ticks are
+// dropped if the reader falls behind. If c cannot wait, or cannot be read,
+// the channel is closed. NewTicker panics if d <= 0.
+func NewTicker(c Clock, d time.Duration) *Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	ch := make(chan time.Time, 1)
+	t := &Ticker{
+		C:    ch,
+		stop: make(chan struct{}),
+	}
+	go t.run(c, d, ch)
+	return t
+}
+
+// Stop turns off the Ticker. No more ticks will be sent after Stop returns,
+// and the Ticker's goroutine exits. The sleep in progress is abandoned on a
+// Manual clock, and on Linux on the clocks WithDeadline waits for with a
+// timerfd; on other clocks it runs to its deadline in the background.
+// Calling Stop more than once has no effect.
+func (t *Ticker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.stopped {
+		t.stopped = true
+		close(t.stop)
+	}
+}
+
+func (t *Ticker) run(c Clock, d time.Duration, ch chan time.Time) {
+	start, err := now(c)
+	if err != nil {
+		close(ch)
+		return
+	}
+	next := start.Add(d)
+	for {
+		switch err := t.sleepUntil(c, next); err {
+		case nil:
+		case errStopped:
+			return
+		default:
+			close(ch)
+			return
+		}
+		tm, err := now(c)
+		if err != nil {
+			close(ch)
+			return
+		}
+		if !t.tick(ch, tm) {
+			return
+		}
+		next = next.Add(d)
+	}
+}
+
+// tick sends now on ch unless the Ticker is stopped, which it reports by
+// returning false.
+func (t *Ticker) tick(ch chan time.Time, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	select {
+	case ch <- now:
+	default:
+		// reader is behind, drop the tick
+	}
+	return true
+}
+
+// sleepUntil is SleepUntil, except that it returns errStopped once the
+// Ticker is stopped, abandoning the wait where wait can.
+func (t *Ticker) sleepUntil(c Clock, next time.Time) error {
+	fired, stop := wait(c, next)
+	defer stop()
+	select {
+	case err := <-fired:
+		return err
+	case <-t.stop:
+		return errStopped
+	}
+}
@@ -0,0 +1,101 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	for _, c := range []Clock{Monotonic, Boottime, Realtime} {
+		start := c.Now()
+		if err := Sleep(c, 10*time.Millisecond); err != nil {
+			t.Fatalf("%v: %v", c, err)
+		}
+		if d := c.Now().Sub(start); d < 10*time.Millisecond {
+			t.Fatalf("%v: woke after %v, expected at least 10ms", c, d)
+		}
+	}
+}
+
+func TestSleepUntilPast(t *testing.T) {
+	if err := SleepUntil(Monotonic, Monotonic.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSleepUnsupported(t *testing.T) {
+	if err := Sleep(Thread, time.Millisecond); err == nil {
+		t.Fatal("expected error sleeping on thread CPU clock")
+	}
+	if err := Sleep(&stepClock{}, time.Millisecond); err != ErrCannotWait {
+		t.Fatalf("got %v, want %v", err, ErrCannotWait)
+	}
+	if _, ok := <-After(&stepClock{}, time.Millisecond); ok {
+		t.Fatal("After: expected closed channel")
+	}
+}
+
+func TestAfter(t *testing.T) {
+	m := NewManual(manualEpoch)
+	c := After(m, time.Second)
+	m.BlockUntil(1)
+	m.Advance(time.Second)
+	if got, want := <-c, manualEpoch.Add(time.Second); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestTicker(t *testing.T) {
+	m := NewManual(manualEpoch)
+	tk := NewTicker(m, time.Second)
+	defer tk.Stop()
+	for i := 1; i <= 3; i++ {
+		m.BlockUntil(1)
+		m.Advance(time.Second)
+		if got, want := <-tk.C, manualEpoch.Add(time.Duration(i)*time.Second); !got.Equal(want) {
+			t.Fatalf("tick %d: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestTickerMonotonic(t *testing.T) {
+	tk := NewTicker(Monotonic, 5*time.Millisecond)
+	defer tk.Stop()
+	prev := <-tk.C
+	for i := 0; i < 3; i++ {
+		next := <-tk.C
+		if d := next.Sub(prev); d <= 0 {
+			t.Fatalf("tick %d: went backwards by %v", i, d)
+		}
+		prev = next
+	}
+}
+
+func TestTickerStop(t *testing.T) {
+	m := NewManual(manualEpoch)
+	tk := NewTicker(m, time.Second)
+	m.BlockUntil(1)
+	tk.Stop()
+	tk.Stop()
+	// the sleep in progress is abandoned.
+	waitPending(t, m, 0)
+	m.Advance(time.Hour)
+	select {
+	case <-tk.C:
+		t.Fatal("tick after Stop")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestTickerNowErr(t *testing.T) {
+	tk := NewTicker(&clock{^uintptr(0) >> 1}, time.Millisecond)
+	defer tk.Stop()
+	select {
+	case _, ok := <-tk.C:
+		if ok {
+			t.Fatal("tick from a clock which cannot be read")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+}
@@ -0,0 +1,84 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// A Stopwatch is a Timer which accumulates the time that elapses on its Clock
+// between calls to Start and Stop. The zero value is not usable, use NewTimer.
+type Stopwatch struct {
+	clock Clock
+
+	mu      sync.Mutex // protects remaining fields
+	running bool
+	start   time.Time     // when the stopwatch was last started
+	elapsed time.Duration // accumulated before start
+	lap     time.Duration // value of Elapsed at the last Lap
+}
+
+// NewTimer returns a stopped Stopwatch which measures time according to c.
+func NewTimer(c Clock) *Stopwatch {
+	return &Stopwatch{clock: c}
+}
+
+// Clock returns the Clock which powers this Stopwatch.
+func (s *Stopwatch) Clock() Clock { return s.clock }
+
+// Start starts the Stopwatch. Calling Start on a running Stopwatch has no effect.
+func (s *Stopwatch) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	s.start = s.clock.Now()
+}
+
+// Stop stops the Stopwatch, and returns the total time elapsed.
+// Calling Stop on a stopped Stopwatch has no effect.
+func (s *Stopwatch) Stop() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		s.elapsed += s.clock.Now().Sub(s.start)
+		s.running = false
+	}
+	return s.elapsed
+}
+
+// Reset stops the Stopwatch and discards any elapsed time.
+func (s *Stopwatch) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	s.elapsed = 0
+	s.lap = 0
+}
+
+// Elapsed returns the total time the Stopwatch has been running since it
+// was created or last Reset.
+func (s *Stopwatch) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.elapsedLocked()
+}
+
+// Lap returns the time the Stopwatch has been running since the previous
+// call to Lap, or since it was created or last Reset.
+func (s *Stopwatch) Lap() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.elapsedLocked()
+	d := e - s.lap
+	s.lap = e
+	return d
+}
+
+func (s *Stopwatch) elapsedLocked() time.Duration {
+	if s.running {
+		return s.elapsed + s.clock.Now().Sub(s.start)
+	}
+	return s.elapsed
+}
@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// stepClock advances by step every time it is read.
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *stepClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestStopwatch(t *testing.T) {
+	c := &stepClock{step: time.Second}
+	s := NewTimer(c)
+	if s.Clock() != c {
+		t.Fatal("Clock did not return the underlying clock")
+	}
+	if e := s.Elapsed(); e != 0 {
+		t.Fatalf("stopped stopwatch: got %v, want 0", e)
+	}
+	s.Start()                            // 1s
+	if e := s.Stop(); e != time.Second { // 2s
+		t.Fatalf("Stop: got %v, want %v", e, time.Second)
+	}
+	if e := s.Elapsed(); e != time.Second {
+		t.Fatalf("Elapsed after Stop: got %v, want %v", e, time.Second)
+	}
+	s.Start()                                 // 3s
+	if e := s.Elapsed(); e != 2*time.Second { // 4s
+		t.Fatalf("Elapsed: got %v, want %v", e, 2*time.Second)
+	}
+	s.Reset()
+	if e := s.Elapsed(); e != 0 {
+		t.Fatalf("Elapsed after Reset: got %v, want 0", e)
+	}
+}
+
+func TestStopwatchLap(t *testing.T) {
+	c := &stepClock{step: time.Second}
+	s := NewTimer(c)
+	s.Start() // 1s
+	for i := 0; i < 3; i++ {
+		if l := s.Lap(); l != time.Second {
+			t.Fatalf("Lap %d: got %v, want %v", i, l, time.Second)
+		}
+	}
+	if e := s.Stop(); e != 4*time.Second {
+		t.Fatalf("Stop: got %v, want %v", e, 4*time.Second)
+	}
+	if l := s.Lap(); l != time.Second {
+		t.Fatalf("Lap after Stop: got %v, want %v", l, time.Second)
+	}
+}
+
+func TestStopwatchMonotonic(t *testing.T) {
+	s := NewTimer(Monotonic)
+	s.Start()
+	time.Sleep(10 * time.Millisecond)
+	e := s.Stop()
+	if e < 10*time.Millisecond || e > time.Minute {
+		t.Fatalf("Elapsed: got %v, expected roughly 10ms", e)
+	}
+}
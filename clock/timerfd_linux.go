@@ -0,0 +1,144 @@
+package clock
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// from /usr/include/linux/timerfd.h
+const (
+	TFD_TIMER_ABSTIME = 1 << 0
+	TFD_CLOEXEC       = syscall.O_CLOEXEC
+	TFD_NONBLOCK      = syscall.O_NONBLOCK
+)
+
+// ErrNotKernelClock is returned when an operation requires one of the
+// Clocks provided by this package, rather than a user supplied Clock.
+var ErrNotKernelClock = errors.New("clock: not a kernel clock")
+
+type itimerspec struct {
+	interval syscall.Timespec
+	value    syscall.Timespec
+}
+
+// TimerFD is a timer backed by a timerfd(2) file descriptor. The descriptor
+// becomes readable when the timer expires, so a TimerFD can be multiplexed
+// with other descriptors; it satisfies poller.Pollable.
+//
+// timerfd supports Realtime, Monotonic, Boottime, RealtimeAlarm and
+// BoottimeAlarm.
+type TimerFD struct {
+	fd    int
+	clock Clock
+}
+
+// NewTimerFD returns a disarmed TimerFD which measures time on c.
+func NewTimerFD(c Clock) (*TimerFD, error) {
+	return newTimerFD(c, TFD_CLOEXEC)
+}
+
+func newTimerFD(c Clock, flags uintptr) (*TimerFD, error) {
+	k, ok := c.(*clock)
+	if !ok {
+		return nil, ErrNotKernelClock
+	}
+	fd, _, e := syscall.Syscall(syscall.SYS_TIMERFD_CREATE, k.clockid, flags, 0)
+	if e != 0 {
+		return nil, os.NewSyscallError("timerfd_create", e)
+	}
+	return &TimerFD{fd: int(fd), clock: c}, nil
+}
+
+// Clock returns the Clock which drives this TimerFD.
+func (t *TimerFD) Clock() Clock { return t.clock }
+
+// Fd returns the timerfd file descriptor.
+func (t *TimerFD) Fd() uintptr { return uintptr(t.fd) }
+
+// Set arms the timer to expire after value, and then every interval. If
+// interval is zero the timer expires once. A zero value disarms the timer.
+func (t *TimerFD) Set(value, interval time.Duration) error {
+	its := itimerspec{
+		interval: syscall.NsecToTimespec(int64(interval)),
+		value:    syscall.NsecToTimespec(int64(value)),
+	}
+	return t.settime(0, &its)
+}
+
+// SetAt arms the timer to expire when its Clock reads deadline, and then
+// every interval. If interval is zero the timer expires once.
+func (t *TimerFD) SetAt(deadline time.Time, interval time.Duration) error {
+	ns := deadline.UnixNano()
+	if ns <= 0 {
+		// a zero value would disarm the timer, expire immediately instead.
+		ns = 1
+	}
+	its := itimerspec{
+		interval: syscall.NsecToTimespec(int64(interval)),
+		value:    syscall.NsecToTimespec(ns),
+	}
+	return t.settime(TFD_TIMER_ABSTIME, &its)
+}
+
+// Stop disarms the timer.
+func (t *TimerFD) Stop() error {
+	return t.Set(0, 0)
+}
+
+// Remaining returns the time until the timer next expires, and its interval.
+// A zero value indicates the timer is disarmed.
+func (t *TimerFD) Remaining() (value, interval time.Duration, err error) {
+	var its itimerspec
+	_, _, e := syscall.Syscall(syscall.SYS_TIMERFD_GETTIME, uintptr(t.fd), uintptr(unsafe.Pointer(&its)), 0)
+	if e != 0 {
+		return 0, 0, os.NewSyscallError("timerfd_gettime", e)
+	}
+	return time.Duration(its.value.Nano()), time.Duration(its.interval.Nano()), nil
+}
+
+// Expirations blocks until the timer has expired at least once, then
+// returns the number of expirations since the timer was set or last read.
+func (t *TimerFD) Expirations() (uint64, error) {
+	var buf [8]byte
+	if _, err := t.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.NativeEndian.Uint64(buf[:]), nil
+}
+
+// Read reads the expiration count as a native endian uint64 into b, which
+// must be at least 8 bytes long.
+func (t *TimerFD) Read(b []byte) (int, error) {
+	for {
+		n, err := syscall.Read(t.fd, b)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("read", err)
+		}
+		return n, nil
+	}
+}
+
+// Write always fails, a TimerFD is not writable.
+func (t *TimerFD) Write(b []byte) (int, error) {
+	return 0, os.NewSyscallError("write", syscall.EINVAL)
+}
+
+// Close closes the timerfd.
+func (t *TimerFD) Close() error {
+	return os.NewSyscallError("close", syscall.Close(t.fd))
+}
+
+func (t *TimerFD) settime(flags uintptr, its *itimerspec) error {
+	_, _, e := syscall.Syscall6(syscall.SYS_TIMERFD_SETTIME, uintptr(t.fd), flags, uintptr(unsafe.Pointer(its)), 0, 0, 0)
+	if e != 0 {
+		return os.NewSyscallError("timerfd_settime", e)
+	}
+	return nil
+}
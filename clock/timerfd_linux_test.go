@@ -0,0 +1,94 @@
+package clock
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// pollable mirrors poller.Pollable.
+type pollable interface {
+	io.ReadWriteCloser
+	Fd() uintptr
+}
+
+var _ pollable = (*TimerFD)(nil)
+
+func mustTimerFD(t *testing.T, c Clock) *TimerFD {
+	tfd, err := NewTimerFD(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tfd
+}
+
+func TestTimerFDOneShot(t *testing.T) {
+	for _, c := range []Clock{Monotonic, Realtime, Boottime} {
+		tfd := mustTimerFD(t, c)
+		if err := tfd.Set(time.Millisecond, 0); err != nil {
+			t.Fatal(err)
+		}
+		n, err := tfd.Expirations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("%v: got %d expirations, want 1", c, n)
+		}
+		if v, _, err := tfd.Remaining(); err != nil || v != 0 {
+			t.Fatalf("%v: Remaining: got %v, %v, expected disarmed", c, v, err)
+		}
+		if err := tfd.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTimerFDInterval(t *testing.T) {
+	tfd := mustTimerFD(t, Monotonic)
+	defer tfd.Close()
+	if err := tfd.Set(time.Millisecond, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	n, err := tfd.Expirations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 2 {
+		t.Fatalf("got %d expirations, expected at least 2", n)
+	}
+	if _, i, err := tfd.Remaining(); err != nil || i != time.Millisecond {
+		t.Fatalf("Remaining: got interval %v, %v, want %v", i, err, time.Millisecond)
+	}
+	if err := tfd.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := tfd.Remaining(); v != 0 {
+		t.Fatalf("Remaining after Stop: got %v, want 0", v)
+	}
+}
+
+func TestTimerFDSetAt(t *testing.T) {
+	tfd := mustTimerFD(t, Monotonic)
+	defer tfd.Close()
+	deadline := Monotonic.Now().Add(5 * time.Millisecond)
+	if err := tfd.SetAt(deadline, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tfd.Expirations(); err != nil {
+		t.Fatal(err)
+	}
+	if now := Monotonic.Now(); now.Before(deadline) {
+		t.Fatalf("expired at %v, before deadline %v", now, deadline)
+	}
+}
+
+func TestTimerFDUnsupported(t *testing.T) {
+	if _, err := NewTimerFD(&stepClock{}); err != ErrNotKernelClock {
+		t.Fatalf("got %v, want %v", err, ErrNotKernelClock)
+	}
+	if _, err := NewTimerFD(Process); err == nil {
+		t.Fatal("expected error creating timerfd on process CPU clock")
+	}
+}
@@ -0,0 +1,142 @@
+package clock
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// The vDSO's clock_gettime is C code which may need more stack than a
+// goroutine has, so vdsoCall runs it on a vdsoStack taken from a pool,
+// much as the runtime runs it on the system stack.
+
+// vdsoStackSize is the size of the stack the vDSO runs on. It is far more
+// than clock_gettime uses, to allow for kernels built with stack probes.
+const vdsoStackSize = 16 << 10
+
+type vdsoStack [vdsoStackSize]byte
+
+var vdsoStacks = sync.Pool{New: func() any { return new(vdsoStack) }}
+
+var (
+	vdsoOnce         sync.Once
+	vdsoClockGettime uintptr // address of clock_gettime in the vDSO, or zero
+)
+
+// vdso returns the address of the vDSO clock_gettime function, or zero if
+// the vDSO cannot be called, in which case gettime falls back to the
+// clock_gettime syscall. The vDSO is looked up on first use.
+func vdso() uintptr {
+	vdsoOnce.Do(func() {
+		vdsoClockGettime, _ = vdsoLookup(vdsoSymbol)
+	})
+	return vdsoClockGettime
+}
+
+// vdsoGettime reads clockid by calling the vDSO clock_gettime function at
+// fn, which makes the syscall itself for clocks it cannot read.
+func vdsoGettime(fn, clockid uintptr) (syscall.Timespec, error) {
+	var ts syscall.Timespec
+	stk := vdsoStacks.Get().(*vdsoStack)
+	ret := vdsoCall(fn, clockid, &ts, stk)
+	vdsoStacks.Put(stk)
+	if ret != 0 {
+		return ts, os.NewSyscallError("clock_gettime", syscall.Errno(-ret))
+	}
+	return ts, nil
+}
+
+var errNoVDSO = errors.New("clock: vDSO not found")
+
+// vdsoLookup returns the address of the named function in the vDSO mapped
+// into this process. The vDSO image is read through /proc/self/mem, so the
+// address can be found without converting it to a pointer.
+func vdsoLookup(name string) (uintptr, error) {
+	if name == "" {
+		return 0, errNoVDSO
+	}
+	start, end, err := vdsoMapping()
+	if err != nil {
+		return 0, err
+	}
+	mem, err := os.Open("/proc/self/mem")
+	if err != nil {
+		return 0, err
+	}
+	defer mem.Close()
+	image := make([]byte, end-start)
+	if _, err := mem.ReadAt(image, int64(start)); err != nil {
+		return 0, err
+	}
+	f, err := elf.NewFile(bytes.NewReader(image))
+	if err != nil {
+		return 0, err
+	}
+	var base uint64 // virtual address the image was linked at
+	found := false
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && p.Off == 0 {
+			base, found = p.Vaddr, true
+			break
+		}
+	}
+	if !found {
+		return 0, errNoVDSO
+	}
+	syms, err := f.DynamicSymbols()
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range syms {
+		if s.Name == name && elf.ST_TYPE(s.Info) == elf.STT_FUNC && s.Value != 0 {
+			return start + uintptr(s.Value-base), nil
+		}
+	}
+	return 0, errNoVDSO
+}
+
+// vdsoMapping returns the address range of the [vdso] mapping.
+func vdsoMapping() (start, end uintptr, err error) {
+	f, err := os.Open("/proc/self/maps")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasSuffix(line, "[vdso]") {
+			continue
+		}
+		addrs := strings.Fields(line)[0]
+		i := strings.IndexByte(addrs, '-')
+		if i < 0 {
+			break
+		}
+		s, err1 := strconv.ParseUint(addrs[:i], 16, 64)
+		e, err2 := strconv.ParseUint(addrs[i+1:], 16, 64)
+		if err := firstErr(err1, err2); err != nil {
+			return 0, 0, err
+		}
+		return uintptr(s), uintptr(e), nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, errNoVDSO
+}
+
+func firstErr(err ...error) error {
+	for _, err := range err {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package clock
+
+import "syscall"
+
+const vdsoSymbol = "__vdso_clock_gettime"
+
+// vdsoCall calls the vDSO clock_gettime function at fn on stk, and returns
+// its result, zero on success or a negated errno.
+//
+//go:noescape
+func vdsoCall(fn, clockid uintptr, ts *syscall.Timespec, stk *vdsoStack) int
@@ -0,0 +1,20 @@
+#include "textflag.h"
+
+// func vdsoCall(fn, clockid uintptr, ts *syscall.Timespec, stk *vdsoStack) int
+//
+// The vDSO is C code, so it runs on a 16 byte aligned stack at the top of
+// stk rather than on the goroutine's stack. Nothing here is a safe point,
+// so the goroutine is neither preempted nor has its stack moved while the
+// vDSO runs, and signals are taken on the signal stack.
+TEXT ·vdsoCall(SB),NOSPLIT,$0-40
+	MOVQ	fn+0(FP), AX
+	MOVQ	clockid+8(FP), DI
+	MOVQ	ts+16(FP), SI
+	MOVQ	stk+24(FP), CX
+	MOVQ	SP, BX		// BX is callee saved in the C ABI
+	LEAQ	16384(CX), SP	// vdsoStackSize
+	ANDQ	$~15, SP
+	CALL	AX
+	MOVQ	BX, SP
+	MOVQ	AX, ret+32(FP)
+	RET
@@ -0,0 +1,13 @@
+//go:build linux && !amd64
+
+package clock
+
+import "syscall"
+
+// vdsoSymbol is empty as the vDSO is only called on amd64, other
+// architectures use the clock_gettime syscall.
+const vdsoSymbol = ""
+
+func vdsoCall(fn, clockid uintptr, ts *syscall.Timespec, stk *vdsoStack) int {
+	panic("clock: vDSO not supported")
+}
@@ -0,0 +1,89 @@
+package clock
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestVDSO(t *testing.T) {
+	fn := vdso()
+	if fn == 0 {
+		if runtime.GOARCH == "amd64" {
+			t.Fatal("vDSO clock_gettime not found")
+		}
+		t.Skip("vDSO not called on", runtime.GOARCH)
+	}
+	for id := range clocks {
+		ts, err := vdsoGettime(fn, uintptr(id))
+		sts, serr := sysGettime(uintptr(id))
+		if (err == nil) != (serr == nil) {
+			t.Fatalf("clock %d: vDSO error %v, syscall error %v", id, err, serr)
+		}
+		if err != nil {
+			continue
+		}
+		if d := time.Duration(sts.Nano() - ts.Nano()); d < 0 || d > time.Second {
+			t.Fatalf("clock %d: vDSO read %v, syscall read %v", id, ts, sts)
+		}
+	}
+	if _, err := vdsoGettime(fn, ^uintptr(0)>>1); err == nil {
+		t.Fatal("expected error from invalid clock")
+	}
+}
+
+// TestVDSOConcurrent reads the clocks from many goroutines while the
+// runtime preempts them and the collector runs.
+func TestVDSOConcurrent(t *testing.T) {
+	done := make(chan bool)
+	for i := 0; i < 8; i++ {
+		go func() {
+			var prev time.Time
+			for j := 0; j < 10000; j++ {
+				now := MonotonicRaw.Now()
+				if now.Before(prev) {
+					done <- false
+					return
+				}
+				prev = now
+			}
+			done <- true
+		}()
+	}
+	for i := 0; i < 8; i++ {
+		runtime.GC()
+		if !<-done {
+			t.Fatal("MonotonicRaw went backwards")
+		}
+	}
+}
+
+func BenchmarkNow(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Monotonic.Now()
+	}
+}
+
+func BenchmarkNowCoarse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MonotonicCoarse.Now()
+	}
+}
+
+func BenchmarkNowRaw(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MonotonicRaw.Now()
+	}
+}
+
+func BenchmarkNowSyscall(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		sysGettime(CLOCK_MONOTONIC)
+	}
+}
+
+func BenchmarkTimeNow(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		time.Now()
+	}
+}
@@ -0,0 +1,183 @@
+package poller
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// from /usr/include/linux/eventpoll.h
+const (
+	EPOLLRDHUP   = 0x2000
+	EPOLLONESHOT = 1 << 30
+	EPOLLET      = 1 << 31
+)
+
+// NewEpoll returns a Poller which uses epoll(7), with descriptors
+// registered according to trigger.
+func NewEpoll(trigger Trigger) (Poller, error) {
+	return newPoller(func(wakefd uintptr) (backend, error) {
+		return newEpollBackend(wakefd, trigger)
+	})
+}
+
+type epollBackend struct {
+	epfd    int
+	trigger Trigger
+	events  [128]syscall.EpollEvent // only used by the loop goroutine
+}
+
+func newEpollBackend(wakefd uintptr, trigger Trigger) (*epollBackend, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, os.NewSyscallError("epoll_create1", err)
+	}
+	e := &epollBackend{epfd: epfd, trigger: trigger}
+	if err := e.ctl(syscall.EPOLL_CTL_ADD, wakefd, syscall.EPOLLIN); err != nil {
+		syscall.Close(epfd)
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *epollBackend) ctl(op int, fd uintptr, events uint32) error {
+	ev := syscall.EpollEvent{Events: events, Fd: int32(fd)}
+	if err := syscall.EpollCtl(e.epfd, op, int(fd), &ev); err != nil {
+		return os.NewSyscallError("epoll_ctl", err)
+	}
+	return nil
+}
+
+// add registers edge triggered descriptors for every event, and level
+// triggered descriptors for no events until a goroutine waits.
+func (e *epollBackend) add(pd *pollDesc) error {
+	if e.trigger == EdgeTriggered {
+		return e.ctl(syscall.EPOLL_CTL_ADD, pd.fd, syscall.EPOLLIN|syscall.EPOLLOUT|EPOLLRDHUP|EPOLLET)
+	}
+	return e.ctl(syscall.EPOLL_CTL_ADD, pd.fd, 0)
+}
+
+func (e *epollBackend) del(pd *pollDesc) {
+	e.ctl(syscall.EPOLL_CTL_DEL, pd.fd, 0)
+}
+
+// update sets the events of a level triggered descriptor to those its
+// waiters are interested in. pd.mu is held across the epoll_ctl so that
+// concurrent updates are applied in order.
+func (e *epollBackend) update(pd *pollDesc) error {
+	if e.trigger == EdgeTriggered {
+		return nil
+	}
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	var events uint32
+	if pd.wait[modeRead] != nil {
+		events |= syscall.EPOLLIN | EPOLLRDHUP
+	}
+	if pd.wait[modeWrite] != nil {
+		events |= syscall.EPOLLOUT
+	}
+	return e.ctl(syscall.EPOLL_CTL_MOD, pd.fd, events)
+}
+
+// watch adds, modifies or removes w in the epoll set to match its
+// interest. Watches are added one shot, so the kernel disarms them when
+// they fire, and they stay in the set until stopped or disarmed.
+func (e *epollBackend) watch(w *Watch) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	switch {
+	case w.stopped:
+		return nil
+	case w.interest == 0:
+		if w.added {
+			w.added = false
+			return e.ctl(syscall.EPOLL_CTL_DEL, w.fd, 0)
+		}
+		return nil
+	case w.added:
+		return e.ctl(syscall.EPOLL_CTL_MOD, w.fd, epollEvents(w.interest)|EPOLLONESHOT)
+	default:
+		if err := e.ctl(syscall.EPOLL_CTL_ADD, w.fd, epollEvents(w.interest)|EPOLLONESHOT); err != nil {
+			return err
+		}
+		w.added = true
+		return nil
+	}
+}
+
+func (e *epollBackend) unwatch(w *Watch) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.added {
+		w.added = false
+		e.ctl(syscall.EPOLL_CTL_DEL, w.fd, 0)
+	}
+}
+
+func (e *epollBackend) close() error {
+	return os.NewSyscallError("close", syscall.Close(e.epfd))
+}
+
+func (e *epollBackend) wait(p *poller, timeout time.Duration) error {
+	msec := -1
+	if timeout >= 0 {
+		// round up, so a short timeout does not become a busy poll.
+		msec = int((timeout + time.Millisecond - 1) / time.Millisecond)
+	}
+	n, err := syscall.EpollWait(e.epfd, e.events[:], msec)
+	if err != nil {
+		return os.NewSyscallError("epoll_wait", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, ev := range e.events[:n] {
+		// a descriptor which cannot be updated has been closed behind
+		// the poller's back, so fails alone.
+		if pd := p.dispatch(uintptr(ev.Fd), epollFlags(ev.Events)); pd != nil {
+			if err := e.update(pd); err != nil {
+				pd.fail(err)
+			}
+		}
+	}
+	return nil
+}
+
+// epollEvents returns the epoll events for interest.
+func epollEvents(interest Interest) uint32 {
+	var events uint32
+	if interest&Readable != 0 {
+		events |= syscall.EPOLLIN | EPOLLRDHUP
+	}
+	if interest&Writable != 0 {
+		events |= syscall.EPOLLOUT
+	}
+	if interest&Priority != 0 {
+		events |= syscall.EPOLLPRI
+	}
+	return events
+}
+
+// epollFlags converts epoll events to the events passed to dispatch.
+func epollFlags(events uint32) int {
+	var ev int
+	if events&syscall.EPOLLIN != 0 {
+		ev |= evRead
+	}
+	if events&syscall.EPOLLOUT != 0 {
+		ev |= evWrite
+	}
+	if events&syscall.EPOLLPRI != 0 {
+		ev |= evPriority
+	}
+	if events&syscall.EPOLLHUP != 0 {
+		ev |= evHangup
+	}
+	if events&EPOLLRDHUP != 0 {
+		ev |= evReadHangup
+	}
+	if events&syscall.EPOLLERR != 0 {
+		ev |= evError
+	}
+	return ev
+}
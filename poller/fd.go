@@ -3,28 +3,34 @@ package poller
 import (
 	"errors"
 	"io"
+	"net"
+	"os"
+	"strconv"
 	"sync"
+	"syscall"
+	"time"
 )
 
 // ReadWriteCloser implementation that supports concurrent Read/Write and Close operations.
 type rwc struct {
 	io.ReadWriteCloser
-	sync.Mutex // protects refcount and closing
+	sync.Mutex // protects refcount, closing and err
 	refcount   int
 	closing    bool
+	closed     chan struct{} // closed once ReadWriteCloser is closed
+	err        error         // returned by ReadWriteCloser.Close
 }
 
-var errClosing = errors.New("closing")
-
 func (c *rwc) incRef(closing bool) error {
 	c.Lock()
 	defer c.Unlock()
 	if c.closing {
-		return errClosing
+		return net.ErrClosed
 	}
 	c.refcount++
 	if closing {
 		c.closing = true
+		c.closed = make(chan struct{})
 	}
 	return nil
 }
@@ -34,16 +40,29 @@ func (c *rwc) decRef() {
 	defer c.Unlock()
 	c.refcount--
 	if c.closing && c.refcount == 0 {
-		c.ReadWriteCloser.Close()
+		c.err = c.ReadWriteCloser.Close()
+		close(c.closed)
 	}
 }
 
+// evicter is implemented by descriptors which can wake the goroutines
+// blocked reading or writing them.
+type evicter interface {
+	evict()
+}
+
+// Close wakes any goroutines blocked in Read or Write, which return
+// net.ErrClosed, then closes the ReadWriteCloser once they have returned.
 func (c *rwc) Close() error {
 	if err := c.incRef(true); err != nil {
 		return err
 	}
+	if e, ok := c.ReadWriteCloser.(evicter); ok {
+		e.evict()
+	}
 	c.decRef()
-	return nil
+	<-c.closed
+	return c.err
 }
 
 func (c *rwc) Read(b []byte) (int, error) {
@@ -61,3 +80,274 @@ func (c *rwc) Write(b []byte) (int, error) {
 	defer c.decRef()
 	return c.ReadWriteCloser.Write(b)
 }
+
+// addrs is implemented by Pollables which know their network addresses.
+type addrs interface {
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+}
+
+// fdAddr is the address of a descriptor without a network address.
+type fdAddr uintptr
+
+func (a fdAddr) Network() string { return "fd" }
+func (a fdAddr) String() string  { return "fd:" + strconv.Itoa(int(a)) }
+
+func (c *rwc) LocalAddr() net.Addr {
+	if a, ok := c.ReadWriteCloser.(addrs); ok {
+		return a.LocalAddr()
+	}
+	return nil
+}
+
+func (c *rwc) RemoteAddr() net.Addr {
+	if a, ok := c.ReadWriteCloser.(addrs); ok {
+		return a.RemoteAddr()
+	}
+	return nil
+}
+
+// deadliner is implemented by descriptors which support deadlines.
+type deadliner interface {
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+var errNoDeadline = errors.New("poller: deadlines not supported")
+
+func (c *rwc) setDeadline(f func(deadliner) error) error {
+	if err := c.incRef(false); err != nil {
+		return err
+	}
+	defer c.decRef()
+	d, ok := c.ReadWriteCloser.(deadliner)
+	if !ok {
+		return errNoDeadline
+	}
+	return f(d)
+}
+
+func (c *rwc) SetDeadline(t time.Time) error {
+	return c.setDeadline(func(d deadliner) error { return d.SetDeadline(t) })
+}
+
+func (c *rwc) SetReadDeadline(t time.Time) error {
+	return c.setDeadline(func(d deadliner) error { return d.SetReadDeadline(t) })
+}
+
+func (c *rwc) SetWriteDeadline(t time.Time) error {
+	return c.setDeadline(func(d deadliner) error { return d.SetWriteDeadline(t) })
+}
+
+const (
+	modeRead = iota
+	modeWrite
+)
+
+// pollDesc is a non-blocking descriptor registered with a poller. Read and
+// Write retry their syscall each time the poller reports the descriptor
+// ready.
+type pollDesc struct {
+	Pollable
+	fd uintptr
+	p  *poller
+
+	rmu, wmu sync.Mutex // serialise Read and Write, so each has at most one waiter
+
+	timers [2]*timer // protected by p.mu
+
+	mu       sync.Mutex       // protects remaining fields
+	ready    [2]bool          // readiness reported while nobody was waiting
+	wait     [2]chan struct{} // closed when the descriptor becomes ready
+	deadline [2]time.Time     // zero if no deadline is set
+	seq      [2]uint64        // incremented each time deadline changes
+	err      error            // if set, returned to every waiter
+}
+
+func (pd *pollDesc) Read(b []byte) (int, error) {
+	pd.rmu.Lock()
+	defer pd.rmu.Unlock()
+	for {
+		if pd.expired(modeRead) {
+			return 0, os.ErrDeadlineExceeded
+		}
+		n, err := syscall.Read(int(pd.fd), b)
+		switch err {
+		case nil:
+			if n == 0 && len(b) > 0 {
+				return 0, io.EOF
+			}
+			return n, nil
+		case syscall.EINTR:
+			// retry
+		case syscall.EAGAIN:
+			if err := pd.waitFor(modeRead); err != nil {
+				return 0, err
+			}
+		default:
+			return 0, os.NewSyscallError("read", err)
+		}
+	}
+}
+
+func (pd *pollDesc) Write(b []byte) (int, error) {
+	pd.wmu.Lock()
+	defer pd.wmu.Unlock()
+	var written int
+	for written < len(b) {
+		if pd.expired(modeWrite) {
+			return written, os.ErrDeadlineExceeded
+		}
+		n, err := syscall.Write(int(pd.fd), b[written:])
+		if n > 0 {
+			written += n
+		}
+		switch err {
+		case nil, syscall.EINTR:
+			// retry any remainder
+		case syscall.EAGAIN:
+			if err := pd.waitFor(modeWrite); err != nil {
+				return written, err
+			}
+		default:
+			return written, os.NewSyscallError("write", err)
+		}
+	}
+	return written, nil
+}
+
+// evict wakes the goroutines waiting on the descriptor, which return
+// net.ErrClosed.
+func (pd *pollDesc) evict() { pd.fail(net.ErrClosed) }
+
+// Close removes the descriptor from the poller and closes the Pollable.
+func (pd *pollDesc) Close() error {
+	pd.p.unregister(pd)
+	return pd.Pollable.Close()
+}
+
+// LocalAddr returns the Pollable's local address, if it has one, or else
+// the descriptor.
+func (pd *pollDesc) LocalAddr() net.Addr {
+	if a, ok := pd.Pollable.(addrs); ok {
+		return a.LocalAddr()
+	}
+	return fdAddr(pd.fd)
+}
+
+// RemoteAddr returns the Pollable's remote address, if it has one, or else
+// the descriptor.
+func (pd *pollDesc) RemoteAddr() net.Addr {
+	if a, ok := pd.Pollable.(addrs); ok {
+		return a.RemoteAddr()
+	}
+	return fdAddr(pd.fd)
+}
+
+func (pd *pollDesc) SetDeadline(t time.Time) error {
+	pd.setDeadline(modeRead, t)
+	pd.setDeadline(modeWrite, t)
+	return nil
+}
+
+func (pd *pollDesc) SetReadDeadline(t time.Time) error {
+	pd.setDeadline(modeRead, t)
+	return nil
+}
+
+func (pd *pollDesc) SetWriteDeadline(t time.Time) error {
+	pd.setDeadline(modeWrite, t)
+	return nil
+}
+
+// setDeadline sets the deadline for mode, and reschedules the timer which
+// wakes any waiter when it passes. A zero t clears the deadline.
+func (pd *pollDesc) setDeadline(mode int, t time.Time) {
+	pd.mu.Lock()
+	pd.deadline[mode] = t
+	pd.seq[mode]++
+	seq := pd.seq[mode]
+	pd.mu.Unlock()
+	pd.p.setTimer(pd, mode, t, seq)
+}
+
+// expired reports whether the deadline for mode has passed.
+func (pd *pollDesc) expired(mode int) bool {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	d := pd.deadline[mode]
+	return !d.IsZero() && !time.Now().Before(d)
+}
+
+// expire wakes the waiter for mode if the deadline which scheduled the
+// timer, identified by seq, is still current.
+func (pd *pollDesc) expire(mode int, seq uint64) {
+	pd.mu.Lock()
+	current := pd.seq[mode] == seq
+	pd.mu.Unlock()
+	if current {
+		pd.notify(mode)
+	}
+}
+
+// waitFor parks the caller until the poller reports the descriptor ready
+// for mode, or the descriptor fails.
+func (pd *pollDesc) waitFor(mode int) error {
+	pd.mu.Lock()
+	if pd.err != nil {
+		pd.mu.Unlock()
+		return pd.err
+	}
+	if pd.ready[mode] {
+		pd.ready[mode] = false
+		pd.mu.Unlock()
+		return nil
+	}
+	c := make(chan struct{})
+	pd.wait[mode] = c
+	pd.mu.Unlock()
+	if err := pd.p.update(pd); err != nil {
+		pd.fail(err)
+	}
+	<-c
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	return pd.err
+}
+
+// waiting reports whether a goroutine is parked waiting for mode.
+func (pd *pollDesc) waiting(mode int) bool {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	return pd.wait[mode] != nil
+}
+
+// notify wakes the goroutine waiting for mode, or records the readiness
+// for the next waiter.
+func (pd *pollDesc) notify(mode int) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if c := pd.wait[mode]; c != nil {
+		close(c)
+		pd.wait[mode] = nil
+		return
+	}
+	pd.ready[mode] = true
+}
+
+// fail wakes every waiter with err, and causes future waits to return it
+// immediately. Only the first error is kept.
+func (pd *pollDesc) fail(err error) {
+	pd.mu.Lock()
+	defer pd.mu.Unlock()
+	if pd.err == nil {
+		pd.err = err
+	}
+	for mode, c := range pd.wait {
+		if c != nil {
+			close(c)
+			pd.wait[mode] = nil
+		}
+	}
+}
@@ -0,0 +1,507 @@
+// Package inotify reports changes to files and directories with
+// inotify(7), reading its descriptor through a poller.Poller so no thread
+// is blocked waiting for events.
+package inotify
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/davecheney/junk/poller"
+)
+
+// ErrOverflow is sent on Errors when the kernel's event queue overflowed,
+// and events were lost.
+var ErrOverflow = errors.New("inotify: event queue overflow")
+
+var errNotWatched = errors.New("inotify: path is not watched")
+
+// Op is the kind of change an Event reports.
+type Op int
+
+const (
+	Create Op = iota + 1
+	Write
+	Remove
+	Rename
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "CREATE"
+	case Write:
+		return "WRITE"
+	case Remove:
+		return "REMOVE"
+	case Rename:
+		return "RENAME"
+	default:
+		return "Op(" + strconv.Itoa(int(op)) + ")"
+	}
+}
+
+// Event is a change to a watched path.
+type Event struct {
+	Op      Op
+	Name    string // the path changed
+	OldName string // for Rename, the path Name was renamed from
+	Dir     bool
+	Cookie  uint32 // for Rename, pairs the halves reported by the kernel
+}
+
+func (e Event) String() string {
+	if e.Op == Rename {
+		return e.Op.String() + " " + e.OldName + " -> " + e.Name
+	}
+	return e.Op.String() + " " + e.Name
+}
+
+// mask is the set of inotify events a Watcher asks for.
+const mask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF
+
+// pairTimeout is how long a Watcher waits for the IN_MOVED_TO half of a
+// rename, before reporting the IN_MOVED_FROM half as a Remove.
+const pairTimeout = 10 * time.Millisecond
+
+// A watch is a path added to the inotify descriptor.
+type watch struct {
+	path      string
+	root      bool // added by Add or AddRecursive, rather than found by recursion
+	recursive bool // subdirectories are watched as they appear
+}
+
+// moved is the IN_MOVED_FROM half of a rename, waiting for its pair.
+type moved struct {
+	cookie uint32
+	path   string
+	dir    bool
+}
+
+// Watcher reports changes to the paths added to it on Events, and failures
+// on Errors. Both channels are closed when the Watcher is closed.
+type Watcher struct {
+	Events <-chan Event
+	Errors <-chan error
+
+	fd     int
+	conn   net.Conn // fd, registered with a poller
+	events chan Event
+	errors chan error
+	done   chan struct{} // closed by Close
+	exited chan struct{} // closed when the reader returns
+
+	mu      sync.Mutex // protects watches, wds and closed, and fd from being closed
+	watches map[int32]*watch
+	wds     map[string]int32
+	closed  bool
+
+	pending *moved // only used by the reader
+}
+
+// New returns a Watcher whose descriptor is read through p.
+func New(p poller.Poller) (*Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	conn, err := p.Register(inotifyFd(fd))
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	w := newWatcher()
+	w.fd = fd
+	w.conn = conn
+	go w.read()
+	return w, nil
+}
+
+func newWatcher() *Watcher {
+	events := make(chan Event)
+	errors := make(chan error)
+	return &Watcher{
+		Events:  events,
+		Errors:  errors,
+		events:  events,
+		errors:  errors,
+		done:    make(chan struct{}),
+		exited:  make(chan struct{}),
+		watches: make(map[int32]*watch),
+		wds:     make(map[string]int32),
+	}
+}
+
+// Add watches path, a file or a directory. The entries of a directory are
+// watched, but not its subdirectories.
+func (w *Watcher) Add(path string) error {
+	_, err := w.add(filepath.Clean(path), true, false)
+	return err
+}
+
+// AddRecursive watches the directory path, and every directory beneath it,
+// including those created or moved in later.
+func (w *Watcher) AddRecursive(path string) error {
+	path = filepath.Clean(path)
+	if _, err := w.add(path, true, true); err != nil {
+		return err
+	}
+	return w.walk(path, false)
+}
+
+// add adds a watch for path.
+func (w *Watcher) add(path string, root, recursive bool) (int32, error) {
+	flags := uint32(mask)
+	if recursive {
+		flags |= syscall.IN_ONLYDIR
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	wd, err := syscall.InotifyAddWatch(w.fd, path, flags)
+	if err != nil {
+		return 0, &os.PathError{Op: "inotify_add_watch", Path: path, Err: err}
+	}
+	if wt, ok := w.watches[int32(wd)]; ok {
+		// the same inode, perhaps by another name.
+		wt.root = wt.root || root
+		wt.recursive = wt.recursive || recursive
+		return int32(wd), nil
+	}
+	w.watches[int32(wd)] = &watch{path: path, root: root, recursive: recursive}
+	w.wds[path] = int32(wd)
+	return int32(wd), nil
+}
+
+// walk adds recursive watches for the directories beneath dir. If create
+// is set, it also reports each entry it finds as created, as they may
+// have appeared before dir was watched.
+func (w *Watcher) walk(dir string, create bool) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// removed while walking
+				return nil
+			}
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		if create && !w.send(Event{Op: Create, Name: path, Dir: d.IsDir()}) {
+			return filepath.SkipAll
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if _, err := w.add(path, false, true); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	})
+}
+
+// Remove stops watching path, and if it was added recursively, the
+// directories beneath it.
+func (w *Watcher) Remove(path string) error {
+	path = filepath.Clean(path)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	wd, ok := w.wds[path]
+	if !ok {
+		return errNotWatched
+	}
+	wds := []int32{wd}
+	if w.watches[wd].recursive {
+		wds = append(wds, w.beneath(path)...)
+	}
+	for _, wd := range wds {
+		w.forget(wd)
+	}
+	var err error
+	for _, wd := range wds {
+		if _, e := syscall.InotifyRmWatch(w.fd, uint32(wd)); e != nil && err == nil {
+			err = os.NewSyscallError("inotify_rm_watch", e)
+		}
+	}
+	return err
+}
+
+// beneath returns the non-root watches below dir. The caller must hold
+// w.mu.
+func (w *Watcher) beneath(dir string) []int32 {
+	var wds []int32
+	for wd, wt := range w.watches {
+		if !wt.root && strings.HasPrefix(wt.path, dir+"/") {
+			wds = append(wds, wd)
+		}
+	}
+	return wds
+}
+
+// forget removes wd from the Watcher's maps. The caller must hold w.mu.
+func (w *Watcher) forget(wd int32) {
+	if wt, ok := w.watches[wd]; ok {
+		delete(w.wds, wt.path)
+		delete(w.watches, wd)
+	}
+}
+
+// Close stops the Watcher and closes its descriptor. A read parked in the
+// poller is interrupted, so Close does not wait for another event. The
+// descriptor is closed only once the reader has returned, as it may be
+// adding or removing watches.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	close(w.done)
+	w.mu.Unlock()
+	err := w.conn.Close()
+	<-w.exited
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if e := syscall.Close(w.fd); e != nil && err == nil {
+		err = os.NewSyscallError("close", e)
+	}
+	return err
+}
+
+// send delivers e on Events, and reports whether the Watcher is still
+// open.
+func (w *Watcher) send(e Event) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// fail delivers err on Errors, and reports whether the Watcher is still
+// open.
+func (w *Watcher) fail(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// read reads events from the descriptor until the Watcher is closed. While
+// half a rename is pending it reads with a deadline, so an unpaired
+// IN_MOVED_FROM is reported promptly.
+func (w *Watcher) read() {
+	defer close(w.exited)
+	defer close(w.errors)
+	defer close(w.events)
+	buf := make([]byte, 64<<10)
+	for {
+		if w.pending != nil {
+			w.conn.SetReadDeadline(time.Now().Add(pairTimeout))
+		} else {
+			w.conn.SetReadDeadline(time.Time{})
+		}
+		n, err := w.conn.Read(buf)
+		switch {
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			if !w.unpaired() {
+				return
+			}
+		case err != nil:
+			select {
+			case <-w.done:
+			default:
+				w.fail(err)
+			}
+			return
+		default:
+			if !w.process(buf[:n]) {
+				return
+			}
+		}
+	}
+}
+
+// process handles the events in buf, and reports whether the Watcher is
+// still open.
+func (w *Watcher) process(buf []byte) bool {
+	// each record is a syscall.InotifyEvent followed by its name.
+	for len(buf) >= syscall.SizeofInotifyEvent {
+		wd := int32(binary.NativeEndian.Uint32(buf[0:]))
+		m := binary.NativeEndian.Uint32(buf[4:])
+		cookie := binary.NativeEndian.Uint32(buf[8:])
+		n := syscall.SizeofInotifyEvent + int(binary.NativeEndian.Uint32(buf[12:]))
+		name := strings.TrimRight(string(buf[syscall.SizeofInotifyEvent:n]), "\x00")
+		buf = buf[n:]
+		if !w.handle(wd, m, cookie, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// handle reports a single inotify event, and reports whether the Watcher
+// is still open.
+func (w *Watcher) handle(wd int32, m, cookie uint32, name string) bool {
+	if m&syscall.IN_Q_OVERFLOW != 0 {
+		return w.unpaired() && w.fail(ErrOverflow)
+	}
+	w.mu.Lock()
+	wt, ok := w.watches[wd]
+	var path string
+	var root, recursive bool
+	if ok {
+		path, root, recursive = wt.path, wt.root, wt.recursive
+	}
+	if m&syscall.IN_IGNORED != 0 {
+		w.forget(wd)
+	}
+	w.mu.Unlock()
+	if !ok {
+		// removed, and this event was already queued.
+		return true
+	}
+	if name != "" {
+		path = filepath.Join(path, name)
+	}
+	dir := m&syscall.IN_ISDIR != 0
+
+	if m&syscall.IN_MOVED_TO != 0 && w.pending != nil && w.pending.cookie == cookie {
+		from := w.pending
+		w.pending = nil
+		if dir {
+			w.renamed(from.path, path)
+		}
+		if !w.send(Event{Op: Rename, Name: path, OldName: from.path, Dir: dir, Cookie: cookie}) {
+			return false
+		}
+		if dir && recursive && !w.watched(path) {
+			// moved in from a directory watched without recursion.
+			if _, err := w.add(path, false, true); err != nil {
+				return os.IsNotExist(err) || w.fail(err)
+			}
+			if err := w.walk(path, false); err != nil {
+				return w.fail(err)
+			}
+		}
+		return true
+	}
+	if !w.unpaired() {
+		return false
+	}
+	switch {
+	case m&syscall.IN_MOVED_FROM != 0:
+		w.pending = &moved{cookie: cookie, path: path, dir: dir}
+		return true
+	case m&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		if !w.send(Event{Op: Create, Name: path, Dir: dir}) {
+			return false
+		}
+		if dir && recursive {
+			if _, err := w.add(path, false, true); err != nil {
+				return os.IsNotExist(err) || w.fail(err)
+			}
+			if err := w.walk(path, true); err != nil {
+				return w.fail(err)
+			}
+		}
+		return true
+	case m&syscall.IN_MODIFY != 0:
+		return w.send(Event{Op: Write, Name: path, Dir: dir})
+	case m&syscall.IN_DELETE != 0:
+		return w.send(Event{Op: Remove, Name: path, Dir: dir})
+	case m&syscall.IN_DELETE_SELF != 0 && root:
+		// a directory found by recursion is reported by its parent.
+		return w.send(Event{Op: Remove, Name: path, Dir: dir})
+	}
+	return true
+}
+
+// unpaired reports a pending IN_MOVED_FROM whose pair has not arrived as
+// a Remove, as the path has moved somewhere which is not watched. It
+// reports whether the Watcher is still open.
+func (w *Watcher) unpaired() bool {
+	from := w.pending
+	if from == nil {
+		return true
+	}
+	w.pending = nil
+	if from.dir {
+		w.mu.Lock()
+		wds := w.beneath(from.path)
+		if wd, ok := w.wds[from.path]; ok && !w.watches[wd].root {
+			wds = append(wds, wd)
+		}
+		for _, wd := range wds {
+			w.forget(wd)
+		}
+		w.mu.Unlock()
+		for _, wd := range wds {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+		}
+	}
+	return w.send(Event{Op: Remove, Name: from.path, Dir: from.dir, Cookie: from.cookie})
+}
+
+// watched reports whether path is watched.
+func (w *Watcher) watched(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.wds[path]
+	return ok
+}
+
+// renamed updates the paths of the watches at and beneath the directory
+// from, which has moved to to.
+func (w *Watcher) renamed(from, to string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, wt := range w.watches {
+		if wt.path != from && !strings.HasPrefix(wt.path, from+"/") {
+			continue
+		}
+		delete(w.wds, wt.path)
+		wt.path = to + strings.TrimPrefix(wt.path, from)
+	}
+	for wd, wt := range w.watches {
+		w.wds[wt.path] = wd
+	}
+}
+
+// inotifyFd is an inotify descriptor, which a Poller can register. Its
+// Close does nothing, the Watcher closes the descriptor itself.
+type inotifyFd int
+
+func (fd inotifyFd) Read(b []byte) (int, error) {
+	n, err := syscall.Read(int(fd), b)
+	if err != nil {
+		return 0, os.NewSyscallError("read", err)
+	}
+	return n, nil
+}
+
+func (fd inotifyFd) Write(b []byte) (int, error) {
+	return 0, os.NewSyscallError("write", syscall.EINVAL)
+}
+
+func (fd inotifyFd) Close() error { return nil }
+func (fd inotifyFd) Fd() uintptr  { return uintptr(fd) }
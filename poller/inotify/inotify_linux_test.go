@@ -0,0 +1,215 @@
+package inotify
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/davecheney/junk/poller"
+)
+
+// newTestWatcher returns a Watcher on a new Poller, which are both closed
+// when the test ends.
+func newTestWatcher(t *testing.T) *Watcher {
+	p, err := poller.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(p)
+	if err != nil {
+		p.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		w.Close()
+		p.Close()
+	})
+	return w
+}
+
+// next returns the next Event from w.
+func next(t *testing.T, w *Watcher) Event {
+	t.Helper()
+	select {
+	case e := <-w.Events:
+		return e
+	case err := <-w.Errors:
+		t.Fatal(err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+// expect fails t unless the next Event from w is want.
+func expect(t *testing.T, w *Watcher, want Event) {
+	t.Helper()
+	if got := next(t, w); got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+// skipTo discards Events from w until one matching op and name arrives.
+func skipTo(t *testing.T, w *Watcher, op Op, name string) Event {
+	t.Helper()
+	for {
+		if e := next(t, w); e.Op == op && e.Name == name {
+			return e
+		}
+	}
+}
+
+func TestCreateWriteRemove(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "f")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, Event{Op: Create, Name: name})
+	f.WriteString("hello")
+	f.Close()
+	expect(t, w, Event{Op: Write, Name: name})
+	os.Remove(name)
+	expect(t, w, Event{Op: Remove, Name: name})
+}
+
+func TestRename(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	a, b := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+	os.WriteFile(a, nil, 0644)
+	expect(t, w, Event{Op: Create, Name: a})
+	if err := os.Rename(a, b); err != nil {
+		t.Fatal(err)
+	}
+	e := next(t, w)
+	if e.Op != Rename || e.Name != b || e.OldName != a || e.Cookie == 0 {
+		t.Fatalf("got %v cookie %d, want %v -> %v with a cookie", e, e.Cookie, a, b)
+	}
+}
+
+func TestRenameUnwatched(t *testing.T) {
+	w := newTestWatcher(t)
+	dir, other := t.TempDir(), t.TempDir()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	a := filepath.Join(dir, "a")
+	os.WriteFile(a, nil, 0644)
+	expect(t, w, Event{Op: Create, Name: a})
+
+	// moving out of the watched directory is a Remove.
+	if err := os.Rename(a, filepath.Join(other, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(t, w); e.Op != Remove || e.Name != a {
+		t.Fatalf("got %v, want %v", e, Event{Op: Remove, Name: a})
+	}
+
+	// moving in is a Create.
+	b := filepath.Join(dir, "b")
+	if err := os.Rename(filepath.Join(other, "a"), b); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, Event{Op: Create, Name: b})
+}
+
+func TestAddRecursive(t *testing.T) {
+	w := newTestWatcher(t)
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddRecursive(root); err != nil {
+		t.Fatal(err)
+	}
+	// an existing subdirectory is watched.
+	old := filepath.Join(root, "old", "f")
+	os.WriteFile(old, nil, 0644)
+	expect(t, w, Event{Op: Create, Name: old})
+
+	// as are new ones, even when created faster than they can be watched.
+	deep := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	f := filepath.Join(deep, "f")
+	os.WriteFile(f, nil, 0644)
+	skipTo(t, w, Create, f)
+	os.WriteFile(f, []byte("x"), 0644)
+	skipTo(t, w, Write, f)
+}
+
+func TestRenameDirectory(t *testing.T) {
+	w := newTestWatcher(t)
+	root := t.TempDir()
+	if err := w.AddRecursive(root); err != nil {
+		t.Fatal(err)
+	}
+	a, b := filepath.Join(root, "a"), filepath.Join(root, "b")
+	os.Mkdir(a, 0755)
+	expect(t, w, Event{Op: Create, Name: a, Dir: true})
+	if err := os.Rename(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if e := next(t, w); e.Op != Rename || e.Name != b || e.OldName != a || !e.Dir {
+		t.Fatalf("got %v, want %v -> %v", e, a, b)
+	}
+	// events beneath the directory carry its new name.
+	f := filepath.Join(b, "f")
+	os.WriteFile(f, nil, 0644)
+	expect(t, w, Event{Op: Create, Name: f})
+}
+
+func TestOverflow(t *testing.T) {
+	w := newWatcher()
+	buf := make([]byte, syscall.SizeofInotifyEvent)
+	binary.NativeEndian.PutUint32(buf[0:], ^uint32(0)) // wd -1
+	binary.NativeEndian.PutUint32(buf[4:], syscall.IN_Q_OVERFLOW)
+	go w.process(buf)
+	select {
+	case err := <-w.Errors:
+		if err != ErrOverflow {
+			t.Fatalf("got %v, want %v", err, ErrOverflow)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for overflow")
+	}
+}
+
+func TestClose(t *testing.T) {
+	w := newTestWatcher(t)
+	dir := t.TempDir()
+	if err := w.Add(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := <-w.Events; ok {
+		t.Fatal("Events not closed")
+	}
+	if _, _, e := syscall.Syscall(syscall.SYS_FCNTL, uintptr(w.fd), syscall.F_GETFD, 0); e != syscall.EBADF {
+		t.Fatalf("descriptor not closed: fcntl returned %v", e)
+	}
+	if err := w.Add(dir); err != os.ErrClosed {
+		t.Fatalf("Add after Close: got %v, want %v", err, os.ErrClosed)
+	}
+	if err := w.Remove(dir); err != os.ErrClosed {
+		t.Fatalf("Remove after Close: got %v, want %v", err, os.ErrClosed)
+	}
+	if err := w.Close(); err != os.ErrClosed {
+		t.Fatalf("second Close: got %v, want %v", err, os.ErrClosed)
+	}
+}
@@ -0,0 +1,144 @@
+package poller
+
+import (
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// from /usr/include/asm-generic/poll.h
+const (
+	POLLIN    = 0x1
+	POLLPRI   = 0x2
+	POLLOUT   = 0x4
+	POLLERR   = 0x8
+	POLLHUP   = 0x10
+	POLLNVAL  = 0x20
+	POLLRDHUP = 0x2000
+)
+
+// NewPoll returns a Poller which uses poll(2). Unlike select it has no
+// ceiling on descriptor numbers, and unlike epoll it accepts any descriptor,
+// including device files which epoll rejects.
+func NewPoll() (Poller, error) {
+	return newPoller(func(uintptr) (backend, error) {
+		return new(pollBackend), nil
+	})
+}
+
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+// pollBackend rebuilds its pollfd array from the waiting goroutines on
+// every call to wait.
+type pollBackend struct {
+	fds []pollFd // only used by the loop goroutine
+}
+
+func (*pollBackend) add(pd *pollDesc) error { return nil }
+
+func (*pollBackend) del(pd *pollDesc) {}
+
+// update wakes the loop, so the next poll includes pd.
+func (*pollBackend) update(pd *pollDesc) error { return pd.p.wakeup() }
+
+// watch wakes the loop, so the next poll includes w.
+func (*pollBackend) watch(w *Watch) error { return w.p.wakeup() }
+
+func (*pollBackend) unwatch(w *Watch) {}
+
+func (*pollBackend) close() error { return nil }
+
+func (b *pollBackend) wait(p *poller, timeout time.Duration) error {
+	b.fds = append(b.fds[:0], pollFd{fd: int32(p.waker.fd()), events: POLLIN})
+	p.mu.Lock()
+	for fd, pd := range p.fds {
+		var events int16
+		if pd.waiting(modeRead) {
+			events |= POLLIN | POLLRDHUP
+		}
+		if pd.waiting(modeWrite) {
+			events |= POLLOUT
+		}
+		if events != 0 {
+			b.fds = append(b.fds, pollFd{fd: int32(fd), events: events})
+		}
+	}
+	for fd, w := range p.watches {
+		if interest := w.armed(); interest != 0 {
+			b.fds = append(b.fds, pollFd{fd: int32(fd), events: pollEvents(interest)})
+		}
+	}
+	p.mu.Unlock()
+	var ts *syscall.Timespec
+	if timeout >= 0 {
+		t := syscall.NsecToTimespec(int64(timeout))
+		ts = &t
+	}
+	n, _, e := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&b.fds[0])), uintptr(len(b.fds)), uintptr(unsafe.Pointer(ts)), 0, 0, 0)
+	if e != 0 {
+		return os.NewSyscallError("ppoll", e)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, pfd := range b.fds {
+		if n == 0 {
+			break
+		}
+		if pfd.revents == 0 {
+			continue
+		}
+		n--
+		if pfd.revents&POLLNVAL != 0 && uintptr(pfd.fd) == p.waker.fd() {
+			return os.NewSyscallError("ppoll", syscall.EBADF)
+		}
+		p.dispatch(uintptr(pfd.fd), pollFlags(pfd.revents))
+	}
+	return nil
+}
+
+// pollEvents returns the poll events for interest.
+func pollEvents(interest Interest) int16 {
+	var events int16
+	if interest&Readable != 0 {
+		events |= POLLIN | POLLRDHUP
+	}
+	if interest&Writable != 0 {
+		events |= POLLOUT
+	}
+	if interest&Priority != 0 {
+		events |= POLLPRI
+	}
+	return events
+}
+
+// pollFlags converts poll revents to the events passed to dispatch.
+func pollFlags(revents int16) int {
+	var ev int
+	if revents&POLLIN != 0 {
+		ev |= evRead
+	}
+	if revents&POLLOUT != 0 {
+		ev |= evWrite
+	}
+	if revents&POLLPRI != 0 {
+		ev |= evPriority
+	}
+	if revents&POLLHUP != 0 {
+		ev |= evHangup
+	}
+	if revents&POLLRDHUP != 0 {
+		ev |= evReadHangup
+	}
+	if revents&POLLERR != 0 {
+		ev |= evError
+	}
+	if revents&POLLNVAL != 0 {
+		ev |= evInvalid
+	}
+	return ev
+}
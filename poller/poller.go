@@ -1,14 +1,340 @@
 // Package poller allows readiness notification
 package poller
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
 
+// Pollable is a file descriptor which can be registered with a Poller.
 type Pollable interface {
 	io.ReadWriteCloser
 	Fd() uintptr
 }
 
+// Poller multiplexes blocking I/O on many descriptors onto a single loop.
 type Poller interface {
-	// Register
-	Register(Pollable) io.ReadWriteCloser
+	// Register places the Pollable's descriptor in non-blocking mode and
+	// returns a net.Conn whose Read and Write park the calling goroutine
+	// until the descriptor is ready, and whose deadlines are enforced by
+	// the Poller. Closing the returned value wakes any goroutine parked in
+	// Read or Write, which return net.ErrClosed, then closes the Pollable.
+	Register(Pollable) (net.Conn, error)
+
+	// Watch arms a Watch which calls h with an Event when fd is ready for
+	// interest. The descriptor should be in non-blocking mode, and may not
+	// also be registered.
+	Watch(fd uintptr, interest Interest, h Handler) (*Watch, error)
+
+	// Close stops the Poller. Goroutines parked in Read or Write on a
+	// registered descriptor return ErrClosed, but the descriptors are not
+	// closed.
+	Close() error
+
+	// Err returns the error which stopped the Poller, ErrClosed if it was
+	// closed, or nil while it is running.
+	Err() error
+
+	// Done returns a channel which is closed once the Poller has stopped,
+	// and every parked goroutine has been woken with Err.
+	Done() <-chan struct{}
+}
+
+// ErrClosed is returned by operations on a Poller which has been closed.
+// It wraps net.ErrClosed, which goroutines parked on a descriptor return
+// when the descriptor itself is closed, so errors.Is(err, net.ErrClosed)
+// reports either.
+var ErrClosed = fmt.Errorf("poller: %w", net.ErrClosed)
+
+// New returns the preferred Poller for this platform, an edge triggered
+// epoll Poller.
+func New() (Poller, error) {
+	return NewEpoll(EdgeTriggered)
+}
+
+// Trigger selects how an epoll Poller reports readiness.
+type Trigger int
+
+const (
+	// EdgeTriggered descriptors are added to the epoll set once, and
+	// report a change in readiness whether or not anybody is waiting.
+	EdgeTriggered Trigger = iota
+
+	// LevelTriggered descriptors report readiness only while a goroutine
+	// is waiting, and are modified in the epoll set as waiters come and go.
+	LevelTriggered
+)
+
+// backend is the readiness mechanism behind a poller.
+type backend interface {
+	// add is called when pd is registered.
+	add(pd *pollDesc) error
+
+	// del is called when pd is unregistered, before it is closed.
+	del(pd *pollDesc)
+
+	// update is called when the goroutines waiting on pd change.
+	update(pd *pollDesc) error
+
+	// watch is called when w's interest changes, and unwatch when w is
+	// stopped. unwatch is called with p.mu held.
+	watch(w *Watch) error
+	unwatch(w *Watch)
+
+	// wait blocks for up to timeout, or indefinitely if timeout is
+	// negative, passing ready descriptors to p.dispatch.
+	wait(p *poller, timeout time.Duration) error
+
+	// close releases the backend's resources.
+	close() error
+}
+
+// events reported by a backend.
+const (
+	evRead = 1 << iota
+	evWrite
+	evPriority
+	evHangup
+	evReadHangup // the peer has shut down writing
+	evError
+	evInvalid // the descriptor is not open
+)
+
+var (
+	errRegistered = errors.New("poller: descriptor already registered")
+	errInvalid    = os.NewSyscallError("poller", syscall.EBADF)
+)
+
+type poller struct {
+	backend
+	waker  *waker
+	exited chan struct{} // closed when run returns
+
+	mu      sync.Mutex // protects fds, watches, timers, closed and err
+	fds     map[uintptr]*pollDesc
+	watches map[uintptr]*Watch
+	timers  timerHeap
+	closed  bool
+	err     error // why the loop stopped
+
+	pending []delivery // only used by the loop goroutine
+}
+
+func newPoller(newBackend func(wakefd uintptr) (backend, error)) (*poller, error) {
+	w, err := newWaker()
+	if err != nil {
+		return nil, err
+	}
+	b, err := newBackend(w.fd())
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	p := poller{
+		backend: b,
+		waker:   w,
+		exited:  make(chan struct{}),
+		fds:     make(map[uintptr]*pollDesc),
+		watches: make(map[uintptr]*Watch),
+	}
+	go p.run()
+	return &p, nil
+}
+
+func (p *poller) Register(pollable Pollable) (net.Conn, error) {
+	fd := pollable.Fd()
+	pd := &pollDesc{
+		Pollable: pollable,
+		fd:       fd,
+		p:        p,
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.available(fd); err != nil {
+		return nil, err
+	}
+	restore, err := setNonblock(fd)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.add(pd); err != nil {
+		restore()
+		return nil, err
+	}
+	p.fds[fd] = pd
+	return &rwc{ReadWriteCloser: pd}, nil
+}
+
+// setNonblock puts fd in non blocking mode, and returns a function which
+// restores its previous mode.
+func setNonblock(fd uintptr) (restore func(), err error) {
+	flags, _, e := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	if e != 0 {
+		return nil, os.NewSyscallError("fcntl", e)
+	}
+	if err := syscall.SetNonblock(int(fd), true); err != nil {
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+	return func() {
+		if flags&syscall.O_NONBLOCK == 0 {
+			syscall.SetNonblock(int(fd), false)
+		}
+	}, nil
+}
+
+// available returns an error if fd cannot be registered or watched. The
+// caller must hold p.mu.
+func (p *poller) available(fd uintptr) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.closed {
+		return ErrClosed
+	}
+	if _, ok := p.fds[fd]; ok {
+		return errRegistered
+	}
+	if _, ok := p.watches[fd]; ok {
+		return errRegistered
+	}
+	return nil
+}
+
+func (p *poller) unregister(pd *pollDesc) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fds[pd.fd] == pd {
+		p.del(pd)
+		delete(p.fds, pd.fd)
+	}
+	p.stopTimers(pd)
+}
+
+func (p *poller) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.closed = true
+	p.mu.Unlock()
+	p.wakeup()
+	<-p.exited
+	err1 := p.backend.close()
+	err2 := p.waker.close()
+	return firstErr(err1, err2)
+}
+
+func (p *poller) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func (p *poller) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *poller) Done() <-chan struct{} { return p.exited }
+
+func (p *poller) run() {
+	defer close(p.exited)
+	for !p.isClosed() {
+		if err := p.loop(p.timeout()); err != nil {
+			p.stop(err)
+			return
+		}
+	}
+	p.stop(ErrClosed)
+}
+
+// stop records err as the reason the loop stopped, fails every registered
+// descriptor with it, and stops every Watch, sending its Handler an Error
+// Event.
+func (p *poller) stop(err error) {
+	p.mu.Lock()
+	p.err = err
+	for _, pd := range p.fds {
+		pd.fail(err)
+	}
+	watches := make([]*Watch, 0, len(p.watches))
+	for fd, w := range p.watches {
+		delete(p.watches, fd)
+		w.mu.Lock()
+		w.stopped = true
+		w.mu.Unlock()
+		watches = append(watches, w)
+	}
+	p.mu.Unlock()
+	// Handlers are called without p.mu held, as for deliver.
+	for _, w := range watches {
+		w.handle(Event{Fd: w.fd, Error: true})
+	}
+}
+
+// loop waits for up to timeout for descriptors to become ready, then
+// expires any deadlines which have passed and delivers watched events.
+func (p *poller) loop(timeout time.Duration) error {
+	// a signal interrupting the wait is not an error, the caller will
+	// wait again with a fresh timeout.
+	if err := p.wait(p, timeout); err != nil && !errors.Is(err, syscall.EINTR) {
+		return err
+	}
+	p.expire(time.Now())
+	p.deliver()
+	return nil
+}
+
+// wakeup interrupts the backend's wait, so the loop notices changes to
+// its timers, descriptors or closed state.
+func (p *poller) wakeup() error { return p.waker.wake() }
+
+// dispatch delivers the events ev reported for fd, and returns the
+// registered descriptor they were delivered to, if any. Events for a
+// Watch are queued for deliver. The caller must hold p.mu.
+func (p *poller) dispatch(fd uintptr, ev int) *pollDesc {
+	if fd == p.waker.fd() {
+		p.waker.drain()
+		return nil
+	}
+	if w := p.watches[fd]; w != nil {
+		if w.fire() {
+			p.pending = append(p.pending, delivery{w: w, e: event(fd, ev)})
+		}
+		return nil
+	}
+	pd := p.fds[fd]
+	if pd == nil {
+		return nil
+	}
+	if ev&evInvalid != 0 {
+		pd.fail(errInvalid)
+		return pd
+	}
+	// a hangup or error wakes both waiters, whose retried syscall
+	// reports it.
+	if ev&(evRead|evReadHangup|evHangup|evError) != 0 {
+		pd.notify(modeRead)
+	}
+	if ev&(evWrite|evHangup|evError) != 0 {
+		pd.notify(modeWrite)
+	}
+	return pd
+}
+
+func firstErr(err ...error) error {
+	for _, err := range err {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
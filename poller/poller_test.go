@@ -1 +1,548 @@
 package poller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// pollers are the Poller implementations every test runs against.
+var pollers = []struct {
+	name string
+	new  func() (Poller, error)
+}{
+	{"select", NewSelect},
+	{"poll", NewPoll},
+	{"epoll-edge", func() (Poller, error) { return NewEpoll(EdgeTriggered) }},
+	{"epoll-level", func() (Poller, error) { return NewEpoll(LevelTriggered) }},
+}
+
+// forEachPoller runs f as a subtest against each Poller implementation.
+func forEachPoller(t *testing.T, f func(t *testing.T, p Poller)) {
+	for _, tt := range pollers {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := tt.new()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer p.Close()
+			f(t, p)
+		})
+	}
+}
+
+// pipe returns both ends of a pipe registered with p.
+func pipe(t testing.TB, p Poller) (r, w net.Conn) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err = p.Register(pr); err != nil {
+		t.Fatal(err)
+	}
+	if w, err = p.Register(pw); err != nil {
+		t.Fatal(err)
+	}
+	return r, w
+}
+
+func TestNew(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(*poller).backend.(*epollBackend); !ok {
+		t.Fatalf("New: got %T backend, want epoll", p.(*poller).backend)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterRead(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+
+		done := make(chan []byte)
+		go func() {
+			buf := make([]byte, 5)
+			n, err := r.Read(buf)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- buf[:n]
+		}()
+		time.Sleep(10 * time.Millisecond) // let the reader park
+		if _, err := w.Write([]byte("hello")); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-done; string(got) != "hello" {
+			t.Fatalf("got %q, want %q", got, "hello")
+		}
+	})
+}
+
+func TestRegisterWrite(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+
+		// more than the pipe buffer, so Write must wait for the reader.
+		data := bytes.Repeat([]byte("x"), 1<<20)
+		go func() {
+			if _, err := w.Write(data); err != nil {
+				t.Error(err)
+			}
+			w.Close()
+		}()
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("read %d bytes, want %d", len(got), len(data))
+		}
+	})
+}
+
+// highPipe returns a pipe whose read end is numbered above FD_SETSIZE.
+func highPipe(t *testing.T) (r, w *os.File) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	fd := FD_SETSIZE + 100
+	if err := syscall.Dup3(int(pr.Fd()), fd, syscall.O_CLOEXEC); err != nil {
+		t.Skip(err)
+	}
+	return os.NewFile(uintptr(fd), "high"), pw
+}
+
+func TestRegisterHighFd(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		pr, pw := highPipe(t)
+		defer pw.Close()
+		syscall.SetNonblock(int(pr.Fd()), false)
+		r, err := p.Register(pr)
+		if _, ok := p.(*poller).backend.(selectBackend); ok {
+			if err != errFdSetSize {
+				t.Fatalf("got %v, want %v", err, errFdSetSize)
+			}
+			if nonblocking(t, pr.Fd()) {
+				t.Fatal("rejected descriptor left non blocking")
+			}
+			pr.Close()
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			pw.Write([]byte("x"))
+		}()
+		buf := make([]byte, 1)
+		if _, err := r.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// nonblocking reports whether fd is in non blocking mode.
+func nonblocking(t *testing.T, fd uintptr) bool {
+	flags, _, e := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	if e != 0 {
+		t.Fatal(e)
+	}
+	return flags&syscall.O_NONBLOCK != 0
+}
+
+func TestRegisterRegularFile(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		f, err := os.Open(os.Args[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r, err := p.Register(f)
+		if err == nil {
+			// select and poll report regular files as always ready.
+			r.Close()
+			return
+		}
+		if nonblocking(t, f.Fd()) {
+			t.Fatalf("descriptor rejected with %v left non blocking", err)
+		}
+	})
+}
+
+func TestRegisterTwice(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pr.Close()
+		defer pw.Close()
+		if _, err := p.Register(pr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Register(pr); err != errRegistered {
+			t.Fatalf("got %v, want %v", err, errRegistered)
+		}
+	})
+}
+
+// benchmarkPingPong measures a round trip between two goroutines over a
+// pair of pipes, while idle goroutines are parked reading other idle
+// pipes.
+func benchmarkPingPong(b *testing.B, newPoller func() (Poller, error), idle int) {
+	p, err := newPoller()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+	// create the active pipes first, so select can reach them.
+	r1, w1 := pipe(b, p)
+	r2, w2 := pipe(b, p)
+	var closers []io.Closer
+	for i := 0; i < idle; i++ {
+		r, w := pipe(b, p)
+		closers = append(closers, r, w)
+		go io.Copy(io.Discard, r)
+	}
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := r1.Read(buf); err != nil {
+				return
+			}
+			w2.Write(buf)
+		}
+	}()
+	buf := make([]byte, 1)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w1.Write(buf)
+		r2.Read(buf)
+	}
+	b.StopTimer()
+	w1.Close()
+	for _, c := range append(closers, r1, r2, w2) {
+		c.Close()
+	}
+}
+
+func BenchmarkPingPong(b *testing.B) {
+	for _, tt := range pollers {
+		for _, idle := range []int{0, 400, 4000} {
+			if tt.name == "select" && idle > 400 {
+				continue // select cannot poll descriptors above FD_SETSIZE
+			}
+			b.Run(fmt.Sprintf("%s/%d", tt.name, idle), func(b *testing.B) {
+				benchmarkPingPong(b, tt.new, idle)
+			})
+		}
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+		r.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+		start := time.Now()
+		_, err := r.Read(make([]byte, 1))
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+			t.Fatalf("got %v, want a timeout", err)
+		}
+		if d := time.Since(start); d < 20*time.Millisecond {
+			t.Fatalf("timed out after %v, want at least 20ms", d)
+		}
+		// an expired deadline fails immediately, even if data is ready.
+		w.Write([]byte("x"))
+		if _, err := r.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("got %v, want %v", err, os.ErrDeadlineExceeded)
+		}
+		r.SetReadDeadline(time.Time{})
+		if _, err := r.Read(make([]byte, 1)); err != nil {
+			t.Fatalf("after clearing deadline: %v", err)
+		}
+	})
+}
+
+func TestReadDeadlineExtended(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+		r.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			r.SetReadDeadline(time.Now().Add(time.Hour))
+			time.Sleep(100 * time.Millisecond)
+			w.Write([]byte("x"))
+		}()
+		if _, err := r.Read(make([]byte, 1)); err != nil {
+			t.Fatalf("got %v, expected the extended deadline to allow the read", err)
+		}
+	})
+}
+
+// pendingTimers returns the number of timers scheduled on p.
+func pendingTimers(p Poller) int {
+	pp := p.(*poller)
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return len(pp.timers)
+}
+
+func TestDeadlineTimers(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer w.Close()
+		// a descriptor has at most one timer per mode.
+		for i := 0; i < 1000; i++ {
+			r.SetReadDeadline(time.Now().Add(time.Hour))
+		}
+		r.SetWriteDeadline(time.Now().Add(time.Hour))
+		if n := pendingTimers(p); n != 2 {
+			t.Fatalf("got %d timers, want 2", n)
+		}
+		r.SetReadDeadline(time.Time{})
+		if n := pendingTimers(p); n != 1 {
+			t.Fatalf("after clearing the read deadline: got %d timers, want 1", n)
+		}
+		r.Close()
+		if n := pendingTimers(p); n != 0 {
+			t.Fatalf("after Close: got %d timers, want 0", n)
+		}
+	})
+}
+
+func TestWriteDeadline(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+		w.SetDeadline(time.Now().Add(20 * time.Millisecond))
+		n, err := w.Write(make([]byte, 1<<20))
+		if nerr, ok := err.(net.Error); !ok || !nerr.Timeout() {
+			t.Fatalf("got %v, want a timeout", err)
+		}
+		if n == 0 || n == 1<<20 {
+			t.Fatalf("wrote %d bytes, expected a partial write", n)
+		}
+	})
+}
+
+func TestCloseFailsWaiters(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+		done := make(chan error)
+		go func() {
+			_, err := r.Read(make([]byte, 1))
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // let the reader park
+		if err := p.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != ErrClosed || !errors.Is(err, net.ErrClosed) {
+			t.Fatalf("Read: got %v, want %v", err, ErrClosed)
+		}
+		<-p.Done()
+		if err := p.Err(); err != ErrClosed {
+			t.Fatalf("Err: got %v, want %v", err, ErrClosed)
+		}
+		if _, err := p.Register(os.Stdin); err != ErrClosed {
+			t.Fatalf("Register: got %v, want %v", err, ErrClosed)
+		}
+	})
+}
+
+// faultBackend returns the errors sent on errs from wait, before
+// delegating to the backend.
+type faultBackend struct {
+	backend
+	errs chan error
+}
+
+func (f faultBackend) wait(p *poller, timeout time.Duration) error {
+	select {
+	case err := <-f.errs:
+		return err
+	default:
+		return f.backend.wait(p, timeout)
+	}
+}
+
+func newFaultPoller(t *testing.T) (*poller, chan error) {
+	errs := make(chan error, 1)
+	p, err := newPoller(func(uintptr) (backend, error) {
+		return faultBackend{backend: selectBackend{}, errs: errs}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, errs
+}
+
+func TestWaitInterrupted(t *testing.T) {
+	p, errs := newFaultPoller(t)
+	defer p.Close()
+	r, w := pipe(t, p)
+	defer r.Close()
+	defer w.Close()
+	errs <- os.NewSyscallError("select", syscall.EINTR)
+	p.wakeup()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte("x"))
+	}()
+	if _, err := r.Read(make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("Err: got %v, want nil", err)
+	}
+}
+
+func TestWaitFailed(t *testing.T) {
+	p, errs := newFaultPoller(t)
+	defer p.Close()
+	r, w := pipe(t, p)
+	defer r.Close()
+	defer w.Close()
+	done := make(chan error)
+	go func() {
+		_, err := r.Read(make([]byte, 1))
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond) // let the reader park
+	fatal := errors.New("fatal")
+	errs <- fatal
+	p.wakeup()
+	<-p.Done()
+	if err := p.Err(); err != fatal {
+		t.Fatalf("Err: got %v, want %v", err, fatal)
+	}
+	if err := <-done; err != fatal {
+		t.Fatalf("Read: got %v, want %v", err, fatal)
+	}
+}
+
+// rawFd is a Pollable over a bare descriptor, so a test can close it
+// behind the poller's back.
+type rawFd int
+
+func (fd rawFd) Read(b []byte) (int, error)  { return syscall.Read(int(fd), b) }
+func (fd rawFd) Write(b []byte) (int, error) { return syscall.Write(int(fd), b) }
+func (fd rawFd) Close() error                { return syscall.Close(int(fd)) }
+func (fd rawFd) Fd() uintptr                 { return uintptr(fd) }
+
+func TestInvalidDescriptor(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		if _, ok := p.(*poller).backend.(*epollBackend); ok {
+			t.Skip("epoll silently forgets closed descriptors")
+		}
+		var fds [2]int
+		if err := syscall.Pipe(fds[:]); err != nil {
+			t.Fatal(err)
+		}
+		defer syscall.Close(fds[1])
+		r, err := p.Register(rawFd(fds[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			_, err := r.Read(make([]byte, 1))
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // let the reader park
+		syscall.Close(fds[0])
+		p.(*poller).wakeup()
+		if err := <-done; !errors.Is(err, syscall.EBADF) {
+			t.Fatalf("Read: got %v, want %v", err, syscall.EBADF)
+		}
+		if err := p.Err(); err != nil {
+			t.Fatalf("Err: got %v, want nil", err)
+		}
+	})
+}
+
+func TestCloseInterruptsRead(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pw.Close()
+		r, err := p.Register(pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan error)
+		go func() {
+			_, err := r.Read(make([]byte, 1))
+			done <- err
+		}()
+		time.Sleep(10 * time.Millisecond) // let the reader park
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-done; err != net.ErrClosed {
+			t.Fatalf("Read: got %v, want %v", err, net.ErrClosed)
+		}
+		if fd := pr.Fd(); fd != ^uintptr(0) {
+			t.Fatalf("Pollable still open as fd %d", fd)
+		}
+		if _, err := r.Read(make([]byte, 1)); err != net.ErrClosed {
+			t.Fatalf("Read after Close: got %v, want %v", err, net.ErrClosed)
+		}
+		if err := r.Close(); err != net.ErrClosed {
+			t.Fatalf("second Close: got %v, want %v", err, net.ErrClosed)
+		}
+	})
+}
+
+func TestCloseInterruptsWrite(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		type result struct {
+			n   int
+			err error
+		}
+		done := make(chan result)
+		go func() {
+			n, err := w.Write(make([]byte, 1<<20))
+			done <- result{n, err}
+		}()
+		time.Sleep(10 * time.Millisecond) // let the writer fill the pipe
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		res := <-done
+		if res.err != net.ErrClosed {
+			t.Fatalf("Write: got %v, want %v", res.err, net.ErrClosed)
+		}
+		if res.n == 0 || res.n == 1<<20 {
+			t.Fatalf("wrote %d bytes, expected a partial write", res.n)
+		}
+	})
+}
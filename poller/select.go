@@ -1,75 +1,148 @@
 package poller
 
 import (
-	"log"
+	"errors"
 	"os"
 	"syscall"
 	"time"
 	"unsafe"
 )
 
-type poller struct {
-	pr, pw *os.File
+// FD_SETSIZE is the number of descriptors a syscall.FdSet can hold,
+// from /usr/include/linux/posix_types.h
+const FD_SETSIZE = 1024
+
+var errFdSetSize = errors.New("poller: descriptor exceeds FD_SETSIZE, use NewPoll or NewEpoll")
+
+// NewSelect returns a Poller which uses select(2). It cannot register
+// descriptors numbered FD_SETSIZE or above.
+func NewSelect() (Poller, error) {
+	return newPoller(func(wakefd uintptr) (backend, error) {
+		if wakefd >= FD_SETSIZE {
+			return nil, errFdSetSize
+		}
+		return selectBackend{}, nil
+	})
 }
 
-func newPoller() (*poller, error) {
-	pr, pw, err := os.Pipe()
-	if err != nil {
-		return nil, err
-	}
-	p := poller{
-		pr: pr,
-		pw: pw,
+// selectBackend rebuilds its descriptor sets from the waiting goroutines on
+// every call to wait.
+type selectBackend struct{}
+
+func (selectBackend) add(pd *pollDesc) error {
+	if pd.fd >= FD_SETSIZE {
+		return errFdSetSize
 	}
-	go p.run()
-	return &p, nil
+	return nil
 }
 
-func (p *poller) Close() error {
-	err1 := p.pr.Close()
-	err2 := p.pw.Close()
-	return firstErr(err1, err2)
-}
+func (selectBackend) del(pd *pollDesc) {}
 
-func (p *poller) run() {
-	for {
-		if err := p.loop(time.Second); err != nil {
-			log.Fatal(err)
-		}
+// update wakes the loop, so the next select includes pd.
+func (selectBackend) update(pd *pollDesc) error { return pd.p.wakeup() }
+
+// watch wakes the loop, so the next select includes w.
+func (selectBackend) watch(w *Watch) error {
+	if w.fd >= FD_SETSIZE {
+		return errFdSetSize
 	}
+	return w.p.wakeup()
 }
 
-var wakebuf [1]byte
+func (selectBackend) unwatch(w *Watch) {}
 
-func (p *poller) wakeup() error {
-	_, err := p.pw.Write(wakebuf[:])
-	return err
-}
+func (selectBackend) close() error { return nil }
 
-func (p *poller) loop(timeout time.Duration) error {
-	var rset, wset syscall.FdSet
+func (selectBackend) wait(p *poller, timeout time.Duration) error {
+	var rset, wset, eset syscall.FdSet
 	var numfd int
-	set(&rset, p.pr.Fd(), &numfd)
-	tv := toTimeval(timeout)
-	n, err := syscall.Select(numfd+1, &rset, &wset, nil, &tv)
+	set(&rset, p.waker.fd(), &numfd)
+	p.mu.Lock()
+	for fd, pd := range p.fds {
+		if pd.waiting(modeRead) {
+			set(&rset, fd, &numfd)
+		}
+		if pd.waiting(modeWrite) {
+			set(&wset, fd, &numfd)
+		}
+	}
+	for fd, w := range p.watches {
+		interest := w.armed()
+		if interest&Readable != 0 {
+			set(&rset, fd, &numfd)
+		}
+		if interest&Writable != 0 {
+			set(&wset, fd, &numfd)
+		}
+		if interest&Priority != 0 {
+			set(&eset, fd, &numfd)
+		}
+	}
+	p.mu.Unlock()
+	var tv *syscall.Timeval
+	if timeout >= 0 {
+		t := toTimeval(timeout)
+		tv = &t
+	}
+	n, err := syscall.Select(numfd+1, &rset, &wset, &eset, tv)
+	if err == syscall.EBADF {
+		return invalid(p, numfd, &rset, &wset, &eset)
+	}
 	if err != nil {
-		return err
+		return os.NewSyscallError("select", err)
 	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	for fd := uintptr(0); n > 0 && fd <= uintptr(numfd); fd++ {
+		var ev int
 		if isset(&rset, fd) {
 			n--
-			log.Println(fd, "read")
+			ev |= evRead
 		}
 		if isset(&wset, fd) {
 			n--
-			log.Println(fd, "write")
+			ev |= evWrite
+		}
+		if isset(&eset, fd) {
+			n--
+			ev |= evPriority
+		}
+		if ev != 0 {
+			p.dispatch(fd, ev)
+		}
+	}
+	return nil
+}
+
+// invalid fails the descriptors in sets which are no longer open. select
+// leaves the sets unmodified when it fails, and does not say which
+// descriptor was bad, so each is checked with fcntl. A bad descriptor may
+// have been unregistered since the sets were built, so only a bad wakeup
+// descriptor is an error.
+func invalid(p *poller, numfd int, sets ...*syscall.FdSet) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for fd := uintptr(0); fd <= uintptr(numfd); fd++ {
+		var in bool
+		for _, s := range sets {
+			in = in || isset(s, fd)
+		}
+		if !in {
+			continue
+		}
+		if _, _, e := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFD, 0); e != syscall.EBADF {
+			continue
 		}
+		if fd == p.waker.fd() {
+			return os.NewSyscallError("select", syscall.EBADF)
+		}
+		p.dispatch(fd, evInvalid)
 	}
 	return nil
 }
 
 func set(set *syscall.FdSet, fd uintptr, n *int) {
-	width := unsafe.Sizeof(set.Bits[0])
+	width := 8 * unsafe.Sizeof(set.Bits[0])
 	index := fd / width
 	offset := fd % width
 	set.Bits[index] |= 1 << offset
@@ -77,10 +150,10 @@ func set(set *syscall.FdSet, fd uintptr, n *int) {
 }
 
 func isset(set *syscall.FdSet, fd uintptr) bool {
-	width := unsafe.Sizeof(set.Bits[0])
+	width := 8 * unsafe.Sizeof(set.Bits[0])
 	index := fd / width
 	offset := fd % width
-	return 1<<offset&set.Bits[index] > 0
+	return 1<<offset&set.Bits[index] != 0
 }
 
 func max(a, b int) int {
@@ -91,12 +164,3 @@ func max(a, b int) int {
 }
 
 func toTimeval(d time.Duration) syscall.Timeval { return syscall.NsecToTimeval(int64(d)) }
-
-func firstErr(err ...error) error {
-	for _, err := range err {
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
@@ -1,21 +1,19 @@
 package poller
 
 import (
-	"os"
 	"testing"
 	"time"
 )
 
 func TestPollerLoop(t *testing.T) {
-	pr, pw, err := os.Pipe()
+	w, err := newWaker()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer pr.Close()
-	defer pw.Close()
+	defer w.close()
 	p := &poller{
-		pr: pr,
-		pw: pw,
+		backend: selectBackend{},
+		waker:   w,
 	}
 	if err := p.loop(time.Millisecond); err != nil {
 		t.Fatal(err)
@@ -23,24 +21,23 @@ func TestPollerLoop(t *testing.T) {
 }
 
 func TestPollerLoop2(t *testing.T) {
-	pr, pw, err := os.Pipe()
+	w, err := newWaker()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer pr.Close()
-	defer pw.Close()
+	defer w.close()
 	p := &poller{
-		pr: pr,
-		pw: pw,
+		backend: selectBackend{},
+		waker:   w,
 	}
-	pw.Write([]byte{0})
+	w.wake()
 	if err := p.loop(time.Second); err != nil {
 		t.Fatal(err)
 	}
 }
 
 func TestNewPoller(t *testing.T) {
-	p, err := newPoller()
+	p, err := NewSelect()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -50,10 +47,10 @@ func TestNewPoller(t *testing.T) {
 }
 
 func TestPollerWakeup(t *testing.T) {
-	p, err := newPoller()
+	p, err := NewSelect()
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer p.Close()
-	p.wakeup()
+	p.(*poller).wakeup()
 }
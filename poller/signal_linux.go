@@ -0,0 +1,409 @@
+package poller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// from /usr/include/linux/signalfd.h
+const (
+	SFD_CLOEXEC  = syscall.O_CLOEXEC
+	SFD_NONBLOCK = syscall.O_NONBLOCK
+)
+
+// from /usr/include/asm-generic/signal-defs.h
+const (
+	SIG_BLOCK   = 0
+	SIG_UNBLOCK = 1
+)
+
+// from /usr/include/asm-generic/siginfo.h
+const (
+	SI_USER   = 0
+	SI_KERNEL = 0x80
+	SI_QUEUE  = -1
+	SI_TIMER  = -2
+	SI_SIGIO  = -5
+	SI_TKILL  = -6
+
+	CLD_EXITED    = 1
+	CLD_KILLED    = 2
+	CLD_DUMPED    = 3
+	CLD_TRAPPED   = 4
+	CLD_STOPPED   = 5
+	CLD_CONTINUED = 6
+)
+
+// P_PID selects a child by pid in waitid(2), from /usr/include/linux/wait.h
+const P_PID = 1
+
+// SizeofSiginfo is the size of a Siginfo record read from a SignalFD.
+const SizeofSiginfo = 128
+
+// Siginfo describes a received signal, in the layout of signalfd_siginfo
+// from /usr/include/linux/signalfd.h. Signals which the Go runtime caught
+// before signalfd could report them carry only Signo, see SignalFD.
+type Siginfo struct {
+	Signo    uint32
+	Errno    int32
+	Code     int32
+	Pid      uint32 // sender, or child for SIGCHLD
+	Uid      uint32
+	Fd       int32
+	Tid      uint32
+	Band     uint32
+	Overrun  uint32
+	Trapno   uint32
+	Status   int32 // exit status or signal for SIGCHLD
+	Int      int32
+	Ptr      uint64
+	Utime    uint64
+	Stime    uint64
+	Addr     uint64
+	AddrLsb  uint16
+	_        uint16
+	Syscall  int32
+	CallAddr uint64
+	Arch     uint32
+	_        [28]byte
+}
+
+// Signal returns the signal number as a syscall.Signal.
+func (si *Siginfo) Signal() syscall.Signal { return syscall.Signal(si.Signo) }
+
+// ReadSiginfo reads one Siginfo record from r, which is a SignalFD or the
+// result of registering one with a Poller.
+func ReadSiginfo(r io.Reader) (Siginfo, error) {
+	var buf [SizeofSiginfo]byte
+	var si Siginfo
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return si, err
+	}
+	err := binary.Read(bytes.NewReader(buf[:]), binary.NativeEndian, &si)
+	return si, err
+}
+
+// SignalFD is a Pollable from which signals are read as Siginfo records.
+//
+// A SignalFD reads a signalfd(2) on a dedicated thread which blocks its
+// signals, so a signal sent to that thread, see Tid, is always reported
+// in full. A signal sent to the process is another matter. The kernel
+// delivers it to any thread which does not block it, and the Go runtime
+// cannot block a signal in every thread: it unblocks SIGHUP, SIGINT,
+// SIGQUIT, SIGABRT, SIGTERM, SIGCHLD, SIGURG and SIGPROF, among others, in
+// each thread it starts, and starts threads with the signal mask the
+// process began with, whatever the signal mask of this one. So a signal
+// sent to the process is almost always caught by the runtime, which
+// discards its siginfo, and is reported with only Signo set; the sender of
+// such a signal cannot be recovered.
+//
+// SIGCHLD is the exception. When the runtime catches it, the SignalFD
+// recovers the child and its status with waitid(2), without reaping the
+// child, and reports each change of a child's state once. A child already
+// reaped, for example by exec.Cmd.Wait, cannot be recovered, and its
+// SIGCHLD is reported with only Signo set.
+//
+// Either way, each signal is read from the SignalFD's descriptor, a pipe,
+// so it can be registered or watched like any other.
+type SignalFD struct {
+	rfd  int      // read end of the pipe
+	w    *os.File // write end of the pipe
+	c    chan os.Signal
+	mask uint64
+	tid  int
+	stop *waker         // stops the signalfd thread
+	wg   sync.WaitGroup // tracks the forwarding goroutines
+
+	mu       sync.Mutex // protects children and closed
+	children map[uint32]childState
+	closed   bool
+}
+
+// childState is the state of a child last reported for SIGCHLD.
+type childState struct {
+	code, status int32
+}
+
+// Signals catches sigs, and returns a SignalFD which reports them. It fails
+// with EINVAL for numbers which are not signals.
+func Signals(sigs ...syscall.Signal) (*SignalFD, error) {
+	var mask uint64
+	notify := make([]os.Signal, len(sigs))
+	for i, sig := range sigs {
+		if sig < 1 || sig > 64 {
+			return nil, os.NewSyscallError("signalfd4", syscall.EINVAL)
+		}
+		mask |= 1 << (uint(sig) - 1)
+		notify[i] = sig
+	}
+	stop, err := newWaker()
+	if err != nil {
+		return nil, err
+	}
+	var fds [2]int
+	if err := syscall.Pipe2(fds[:], syscall.O_CLOEXEC); err != nil {
+		stop.close()
+		return nil, os.NewSyscallError("pipe2", err)
+	}
+	if err := syscall.SetNonblock(fds[1], true); err != nil {
+		stop.close()
+		syscall.Close(fds[0])
+		syscall.Close(fds[1])
+		return nil, os.NewSyscallError("setnonblock", err)
+	}
+	s := &SignalFD{
+		rfd:      fds[0],
+		w:        os.NewFile(uintptr(fds[1]), "signals"),
+		c:        make(chan os.Signal, 16),
+		mask:     mask,
+		stop:     stop,
+		children: make(map[uint32]childState),
+	}
+	signal.Notify(s.c, notify...)
+	s.wg.Add(2)
+	go s.forwardCaught()
+	started := make(chan error)
+	go s.forwardQueued(started)
+	if err := <-started; err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// forwardCaught writes a record for each signal caught by the runtime, or
+// for SIGCHLD, one for each child whose state changed.
+func (s *SignalFD) forwardCaught() {
+	defer s.wg.Done()
+	for sig := range s.c {
+		sig := sig.(syscall.Signal)
+		var sis []Siginfo
+		if sig == syscall.SIGCHLD {
+			sis = s.waitChildren()
+		}
+		if len(sis) == 0 {
+			sis = []Siginfo{{Signo: uint32(sig)}}
+		}
+		for _, si := range sis {
+			var buf bytes.Buffer
+			binary.Write(&buf, binary.NativeEndian, si)
+			s.w.Write(buf.Bytes())
+		}
+	}
+}
+
+// forwardQueued blocks the signals on a dedicated thread, and copies
+// records from a signalfd read on that thread until stopped. The thread's
+// signal mask differs from the runtime's, so it is never unlocked, and
+// exits with the goroutine. Each write is no more than PIPE_BUF, so whole
+// records are not interleaved with forwardCaught's.
+func (s *SignalFD) forwardQueued(started chan<- error) {
+	defer s.wg.Done()
+	runtime.LockOSThread()
+	if _, _, e := syscall.RawSyscall6(syscall.SYS_RT_SIGPROCMASK, SIG_BLOCK, uintptr(unsafe.Pointer(&s.mask)), 0, unsafe.Sizeof(s.mask), 0, 0); e != 0 {
+		started <- os.NewSyscallError("rt_sigprocmask", e)
+		return
+	}
+	fd, _, e := syscall.RawSyscall6(syscall.SYS_SIGNALFD4, ^uintptr(0), uintptr(unsafe.Pointer(&s.mask)), unsafe.Sizeof(s.mask), SFD_CLOEXEC|SFD_NONBLOCK, 0, 0)
+	if e != 0 {
+		started <- os.NewSyscallError("signalfd4", e)
+		return
+	}
+	defer syscall.Close(int(fd))
+	s.tid = syscall.Gettid()
+	started <- nil
+
+	fds := []pollFd{
+		{fd: int32(fd), events: POLLIN},
+		{fd: int32(s.stop.fd()), events: POLLIN},
+	}
+	var buf [16 * SizeofSiginfo]byte
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&fds[0])), uintptr(len(fds)), 0, 0, 0, 0)
+		if e != 0 && e != syscall.EINTR {
+			return
+		}
+		if fds[1].revents != 0 {
+			return
+		}
+		n, err := syscall.Read(int(fd), buf[:])
+		if err != nil {
+			continue
+		}
+		s.w.Write(s.unseen(buf[:n]))
+	}
+}
+
+// unseen returns the records in b, less any SIGCHLD for a child state
+// already reported by waitChildren.
+func (s *SignalFD) unseen(b []byte) []byte {
+	out := b[:0]
+	for ; len(b) >= SizeofSiginfo; b = b[SizeofSiginfo:] {
+		r := b[:SizeofSiginfo]
+		var si Siginfo
+		binary.Read(bytes.NewReader(r), binary.NativeEndian, &si)
+		if si.Signal() != syscall.SIGCHLD || si.Code <= SI_USER || s.report(si) {
+			out = append(out, r...)
+		}
+	}
+	return out
+}
+
+// report records si as the last state reported for its child, and reports
+// whether it differs from the state already reported.
+func (s *SignalFD) report(si Siginfo) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := childState{si.Code, si.Status}
+	if s.children[si.Pid] == st {
+		return false
+	}
+	s.children[si.Pid] = st
+	return true
+}
+
+// waitChildren returns a Siginfo for each child whose state has changed
+// since it was last reported, found with waitid(2) and WNOWAIT so the
+// child is left for its owner to reap. Reaped children are forgotten.
+func (s *SignalFD) waitChildren() []Siginfo {
+	pids, err := children()
+	if err != nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	known := make(map[uint32]childState)
+	var sis []Siginfo
+	for _, pid := range pids {
+		si, ok := waitid(pid)
+		if !ok {
+			continue
+		}
+		st := childState{si.Code, si.Status}
+		if s.children[si.Pid] != st {
+			sis = append(sis, si)
+		}
+		known[si.Pid] = st
+	}
+	s.children = known
+	return sis
+}
+
+// children returns the pids of this process's children, as listed by each
+// of its threads in /proc.
+func children() ([]int, error) {
+	tasks, err := filepath.Glob("/proc/self/task/*/children")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, task := range tasks {
+		b, err := os.ReadFile(task)
+		if err != nil {
+			// the thread has exited.
+			continue
+		}
+		for _, f := range strings.Fields(string(b)) {
+			if pid, err := strconv.Atoi(f); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids, nil
+}
+
+// waitid returns the Siginfo of the child pid if its state has changed,
+// without reaping it.
+func waitid(pid int) (Siginfo, bool) {
+	var buf [SizeofSiginfo]byte
+	const options = syscall.WEXITED | syscall.WSTOPPED | syscall.WCONTINUED | syscall.WNOHANG | syscall.WNOWAIT
+	for {
+		_, _, e := syscall.Syscall6(syscall.SYS_WAITID, P_PID, uintptr(pid), uintptr(unsafe.Pointer(&buf[0])), options, 0, 0)
+		if e == syscall.EINTR {
+			continue
+		}
+		if e != 0 {
+			return Siginfo{}, false
+		}
+		si := sigchld(buf[:])
+		// with WNOHANG, a child whose state has not changed leaves the
+		// siginfo zeroed.
+		return si, si.Pid != 0
+	}
+}
+
+// sigchld decodes the kernel siginfo_t waitid(2) reports for a child.
+func sigchld(b []byte) Siginfo {
+	ne := binary.NativeEndian
+	// the union of per signal fields follows the three ints, aligned to
+	// the size of a pointer.
+	u := b[unsafe.Sizeof(uintptr(0))+8:]
+	return Siginfo{
+		Signo:  ne.Uint32(b[0:]),
+		Code:   int32(ne.Uint32(b[8:])),
+		Pid:    ne.Uint32(u[0:]),
+		Uid:    ne.Uint32(u[4:]),
+		Status: int32(ne.Uint32(u[8:])),
+	}
+}
+
+// Tid returns the id of the thread which reads the signalfd. Signals sent
+// to it, with tgkill(2) or rt_tgsigqueueinfo(2), are reported in full.
+func (s *SignalFD) Tid() int { return s.tid }
+
+// Fd returns the descriptor from which records are read.
+func (s *SignalFD) Fd() uintptr { return uintptr(s.rfd) }
+
+// Read reads whole or partial Siginfo records. Use ReadSiginfo to decode
+// them.
+func (s *SignalFD) Read(b []byte) (int, error) {
+	for {
+		n, err := syscall.Read(s.rfd, b)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			return 0, os.NewSyscallError("read", err)
+		}
+		if n == 0 && len(b) > 0 {
+			return 0, io.EOF
+		}
+		return n, nil
+	}
+}
+
+// Write always fails, a SignalFD is not writable.
+func (s *SignalFD) Write(b []byte) (int, error) {
+	return 0, os.NewSyscallError("write", syscall.EINVAL)
+}
+
+// Close stops catching the signals, and closes the descriptors. Signals
+// not yet read are discarded. Closing a SignalFD twice returns
+// os.ErrClosed.
+func (s *SignalFD) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return os.ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+	signal.Stop(s.c)
+	close(s.c)
+	s.stop.wake()
+	// a forwarder blocked writing to a full pipe fails once the read
+	// end is closed, so close it before waiting.
+	err := os.NewSyscallError("close", syscall.Close(s.rfd))
+	s.wg.Wait()
+	return firstErr(err, s.w.Close(), s.stop.close())
+}
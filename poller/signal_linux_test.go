@@ -0,0 +1,197 @@
+package poller
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestSiginfoSize(t *testing.T) {
+	if n := unsafe.Sizeof(Siginfo{}); n != SizeofSiginfo {
+		t.Fatalf("Sizeof(Siginfo): got %d, want %d", n, SizeofSiginfo)
+	}
+}
+
+// expectSender fails t unless si reports sig sent by this process with
+// code.
+func expectSender(t *testing.T, si Siginfo, sig syscall.Signal, code int32) {
+	t.Helper()
+	if si.Signal() != sig || si.Code != code || int(si.Pid) != os.Getpid() || int(si.Uid) != os.Getuid() {
+		t.Fatalf("got signal %v code %d pid %d uid %d, want %v code %d pid %d uid %d",
+			si.Signal(), si.Code, si.Pid, si.Uid, sig, code, os.Getpid(), os.Getuid())
+	}
+}
+
+// expectChild fails t unless si reports that the child pid exited with
+// status.
+func expectChild(t *testing.T, si Siginfo, pid, status int) {
+	t.Helper()
+	if si.Signal() != syscall.SIGCHLD || si.Code != CLD_EXITED || int(si.Pid) != pid || int(si.Status) != status {
+		t.Fatalf("got signal %v code %d child %d status %d, want %v code %d child %d status %d",
+			si.Signal(), si.Code, si.Pid, si.Status, syscall.SIGCHLD, CLD_EXITED, pid, status)
+	}
+}
+
+// exit starts a child which exits with status, and returns it unreaped.
+func exit(t *testing.T, status int) *os.Process {
+	t.Helper()
+	p, err := os.StartProcess("/bin/sh", []string{"sh", "-c", "exit " + strconv.Itoa(status)}, &os.ProcAttr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSignalsRegister(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		s, err := Signals(syscall.SIGUSR1, syscall.SIGHUP)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := p.Register(s)
+		if err != nil {
+			s.Close()
+			t.Fatal(err)
+		}
+		defer r.Close()
+		for _, sig := range []syscall.Signal{syscall.SIGUSR1, syscall.SIGHUP} {
+			go func() {
+				time.Sleep(10 * time.Millisecond) // let the reader park
+				syscall.Tgkill(os.Getpid(), s.Tid(), sig)
+			}()
+			si, err := ReadSiginfo(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectSender(t, si, sig, SI_TKILL)
+		}
+	})
+}
+
+func TestSignalsQueue(t *testing.T) {
+	s, err := Signals(syscall.SIGUSR2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// a kernel siginfo_t for SI_QUEUE carrying 42.
+	var info [SizeofSiginfo]byte
+	*(*int32)(unsafe.Pointer(&info[0])) = int32(syscall.SIGUSR2)
+	*(*int32)(unsafe.Pointer(&info[8])) = SI_QUEUE
+	u := unsafe.Sizeof(uintptr(0)) + 8
+	*(*uint32)(unsafe.Pointer(&info[u])) = uint32(os.Getpid())
+	*(*uint32)(unsafe.Pointer(&info[u+4])) = uint32(os.Getuid())
+	*(*int32)(unsafe.Pointer(&info[u+8])) = 42
+	if _, _, e := syscall.Syscall6(syscall.SYS_RT_TGSIGQUEUEINFO, uintptr(os.Getpid()), uintptr(s.Tid()), uintptr(syscall.SIGUSR2), uintptr(unsafe.Pointer(&info[0])), 0, 0); e != 0 {
+		t.Fatal(os.NewSyscallError("rt_tgsigqueueinfo", e))
+	}
+	si, err := ReadSiginfo(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSender(t, si, syscall.SIGUSR2, SI_QUEUE)
+	if si.Int != 42 {
+		t.Fatalf("got value %d, want 42", si.Int)
+	}
+}
+
+func TestSignalsWatch(t *testing.T) {
+	p, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	s, err := Signals(syscall.SIGCHLD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := make(chan Event, 1)
+	w, err := p.Watch(s.Fd(), Readable, Chan(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	child := exit(t, 3)
+	nextEvent(t, c)
+	si, err := ReadSiginfo(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectChild(t, si, child.Pid, 3)
+	child.Wait()
+}
+
+func TestSignalsChildren(t *testing.T) {
+	s, err := Signals(syscall.SIGCHLD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// each child is reaped only after its state is read, and is
+	// reported once, so each record is for the latest child.
+	for i := 0; i < 5; i++ {
+		child := exit(t, i)
+		si, err := ReadSiginfo(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectChild(t, si, child.Pid, i)
+		child.Wait()
+	}
+}
+
+func TestSignalsProcess(t *testing.T) {
+	s, err := Signals(syscall.SIGUSR2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	si, err := ReadSiginfo(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the runtime usually catches a signal sent to the process, and
+	// only its number is known.
+	if si.Signal() != syscall.SIGUSR2 || si.Pid != 0 && int(si.Pid) != os.Getpid() {
+		t.Fatalf("got signal %v pid %d, want %v", si.Signal(), si.Pid, syscall.SIGUSR2)
+	}
+}
+
+func TestSignalsClose(t *testing.T) {
+	s1, err := Signals(syscall.SIGUSR2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Signals(syscall.SIGUSR2)
+	if err != nil {
+		s1.Close()
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if err := s1.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.Close(); err != os.ErrClosed {
+		t.Fatalf("second Close: got %v, want %v", err, os.ErrClosed)
+	}
+	syscall.Tgkill(os.Getpid(), s2.Tid(), syscall.SIGUSR2)
+	si, err := ReadSiginfo(s2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSender(t, si, syscall.SIGUSR2, SI_TKILL)
+}
+
+func TestSignalsInvalid(t *testing.T) {
+	for _, sig := range []syscall.Signal{0, 65} {
+		if s, err := Signals(sig); err == nil {
+			s.Close()
+			t.Fatalf("%v: expected an error", sig)
+		}
+	}
+}
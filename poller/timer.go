@@ -0,0 +1,110 @@
+package poller
+
+import (
+	"container/heap"
+	"time"
+)
+
+// A timer expires a deadline set on a pollDesc. Each pollDesc has at most
+// one timer per mode, which is moved within the heap when its deadline
+// changes.
+type timer struct {
+	when  time.Time
+	pd    *pollDesc
+	mode  int
+	seq   uint64 // pd.seq[mode] when the deadline was set
+	index int    // position in the heap, or -1 if not scheduled
+}
+
+// timerHeap is a min-heap of timers ordered by when.
+type timerHeap []*timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].when.Before(h[j].when) }
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x interface{}) {
+	t := x.(*timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() interface{} {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	t.index = -1
+	return t
+}
+
+// setTimer schedules the timer for pd's mode to expire at when, or cancels
+// it if when is zero, and wakes the loop if it is now the earliest timer.
+// A call for a deadline older than the timer's, identified by seq, is
+// ignored, as it lost a race with a later setDeadline.
+func (p *poller) setTimer(pd *pollDesc, mode int, when time.Time, seq uint64) {
+	p.mu.Lock()
+	t := pd.timers[mode]
+	if t == nil {
+		t = &timer{pd: pd, mode: mode, index: -1}
+		pd.timers[mode] = t
+	}
+	if seq < t.seq {
+		p.mu.Unlock()
+		return
+	}
+	t.when, t.seq = when, seq
+	switch {
+	case when.IsZero():
+		if t.index >= 0 {
+			heap.Remove(&p.timers, t.index)
+		}
+	case t.index >= 0:
+		heap.Fix(&p.timers, t.index)
+	default:
+		heap.Push(&p.timers, t)
+	}
+	first := len(p.timers) > 0 && p.timers[0] == t
+	p.mu.Unlock()
+	if first {
+		p.wakeup()
+	}
+}
+
+// stopTimers cancels pd's timers. The caller must hold p.mu.
+func (p *poller) stopTimers(pd *pollDesc) {
+	for _, t := range pd.timers {
+		if t != nil && t.index >= 0 {
+			heap.Remove(&p.timers, t.index)
+		}
+	}
+}
+
+// timeout returns how long the loop may wait before the earliest timer
+// expires, or -1 if there are no timers.
+func (p *poller) timeout() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.timers) == 0 {
+		return -1
+	}
+	if d := time.Until(p.timers[0].when); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// expire wakes the waiters whose deadline is at or before now.
+func (p *poller) expire(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.timers) > 0 && !p.timers[0].when.After(now) {
+		t := heap.Pop(&p.timers).(*timer)
+		t.pd.expire(t.mode, t.seq)
+	}
+}
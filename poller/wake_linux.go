@@ -0,0 +1,113 @@
+package poller
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// from /usr/include/linux/eventfd.h
+const (
+	EFD_CLOEXEC  = syscall.O_CLOEXEC
+	EFD_NONBLOCK = syscall.O_NONBLOCK
+)
+
+// A waker interrupts a backend's wait. Wakeups are coalesced, so however
+// many arrive while the loop is busy, the loop sees at most one.
+type waker struct {
+	rfd, wfd int    // the same descriptor for an eventfd
+	buf      []byte // written by wake
+	pending  int32  // set while a wakeup is unread, updated atomically
+
+	mu     sync.RWMutex // held for reading while writing, so close cannot race with wake
+	closed bool
+}
+
+// newWaker returns an eventfd waker, or a pipe waker on kernels without
+// eventfd.
+func newWaker() (*waker, error) {
+	w, err := newEventfdWaker()
+	if err == nil {
+		return w, nil
+	}
+	return newPipeWaker()
+}
+
+func newEventfdWaker() (*waker, error) {
+	fd, _, e := syscall.RawSyscall(syscall.SYS_EVENTFD2, 0, EFD_CLOEXEC|EFD_NONBLOCK, 0)
+	if e != 0 {
+		return nil, os.NewSyscallError("eventfd2", e)
+	}
+	buf := make([]byte, 8)
+	binary.NativeEndian.PutUint64(buf, 1)
+	return &waker{rfd: int(fd), wfd: int(fd), buf: buf}, nil
+}
+
+func newPipeWaker() (*waker, error) {
+	var fds [2]int
+	if err := syscall.Pipe2(fds[:], syscall.O_CLOEXEC|syscall.O_NONBLOCK); err != nil {
+		return nil, os.NewSyscallError("pipe2", err)
+	}
+	return &waker{rfd: fds[0], wfd: fds[1], buf: []byte{0}}, nil
+}
+
+// fd returns the descriptor which becomes readable when woken.
+func (w *waker) fd() uintptr { return uintptr(w.rfd) }
+
+// wake makes fd readable, unless a wakeup is already pending.
+func (w *waker) wake() error {
+	if !atomic.CompareAndSwapInt32(&w.pending, 0, 1) {
+		return nil
+	}
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	for {
+		_, err := syscall.Write(w.wfd, w.buf)
+		switch err {
+		case syscall.EINTR:
+			// retry
+		case nil, syscall.EAGAIN:
+			// a full pipe or counter is already readable.
+			return nil
+		default:
+			return os.NewSyscallError("write", err)
+		}
+	}
+}
+
+// drain consumes every pending wakeup, then clears the pending flag. A
+// wake which saw the flag set did so before it was cleared, so the state
+// it announces is visible to the loop's next wait, and a later wake makes
+// fd readable again.
+func (w *waker) drain() {
+	var buf [64]byte
+	for {
+		_, err := syscall.Read(w.rfd, buf[:])
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || w.rfd == w.wfd {
+			// an eventfd is reset by a single read.
+			break
+		}
+	}
+	atomic.StoreInt32(&w.pending, 0)
+}
+
+// close closes the descriptors. wake must not write to them afterwards,
+// as their numbers may be reused.
+func (w *waker) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	err := syscall.Close(w.rfd)
+	if w.wfd != w.rfd {
+		err = firstErr(err, syscall.Close(w.wfd))
+	}
+	return os.NewSyscallError("close", err)
+}
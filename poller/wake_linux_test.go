@@ -0,0 +1,63 @@
+package poller
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// readable reports whether fd is readable, without blocking.
+func readable(t *testing.T, fd uintptr) bool {
+	pfd := pollFd{fd: int32(fd), events: POLLIN}
+	var ts syscall.Timespec
+	n, _, e := syscall.Syscall6(syscall.SYS_PPOLL, uintptr(unsafe.Pointer(&pfd)), 1, uintptr(unsafe.Pointer(&ts)), 0, 0, 0)
+	if e != 0 {
+		t.Fatal(e)
+	}
+	return n == 1
+}
+
+func TestWaker(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		new  func() (*waker, error)
+	}{
+		{"eventfd", newEventfdWaker},
+		{"pipe", newPipeWaker},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := tt.new()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if readable(t, w.fd()) {
+				t.Fatal("readable before wake")
+			}
+			// wakeups coalesce, so many do not fill the pipe.
+			for i := 0; i < 1<<17; i++ {
+				if err := w.wake(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !readable(t, w.fd()) {
+				t.Fatal("not readable after wake")
+			}
+			w.drain()
+			if readable(t, w.fd()) {
+				t.Fatal("readable after drain")
+			}
+			w.wake()
+			if !readable(t, w.fd()) {
+				t.Fatal("not readable after a wake following drain")
+			}
+			w.drain()
+			if err := w.close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.wake(); err != os.ErrClosed {
+				t.Fatalf("wake after close: got %v, want %v", err, os.ErrClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package poller
+
+import (
+	"errors"
+	"sync"
+)
+
+// Interest is the set of readiness conditions a Watch is armed for.
+type Interest int
+
+const (
+	Readable Interest = 1 << iota
+	Writable
+	Priority // out of band or urgent data
+)
+
+// Event reports the readiness of a watched descriptor. Hangup and Error
+// are reported whatever the Watch's interest, and events may be spurious,
+// so the descriptor's next operation may still return EAGAIN.
+type Event struct {
+	Fd       uintptr
+	Readable bool
+	Writable bool
+	Hangup   bool // the peer has closed the connection, or one half of it
+	Error    bool // the descriptor has a pending error, is not open, or the Poller stopped
+	Priority bool
+}
+
+// event converts the events ev reported by a backend for fd to an Event.
+func event(fd uintptr, ev int) Event {
+	return Event{
+		Fd:       fd,
+		Readable: ev&evRead != 0,
+		Writable: ev&evWrite != 0,
+		Hangup:   ev&(evHangup|evReadHangup) != 0,
+		Error:    ev&(evError|evInvalid) != 0,
+		Priority: ev&evPriority != 0,
+	}
+}
+
+// A Handler receives the events of a Watch. Handle is called on the
+// Poller's loop goroutine, so it must not block.
+type Handler interface {
+	Handle(Event)
+}
+
+// HandlerFunc adapts a function to a Handler.
+type HandlerFunc func(Event)
+
+func (f HandlerFunc) Handle(e Event) { f(e) }
+
+// Chan is a Handler which sends each Event on the channel. A Watch never
+// blocks the Poller's loop sending to a Chan: an Event the channel has no
+// room for is queued on the Watch, and sent by another goroutine, in order,
+// once there is room. No Event is dropped unless the Watch is stopped.
+type Chan chan<- Event
+
+func (c Chan) Handle(e Event) { c <- e }
+
+var errStopped = errors.New("poller: watch stopped")
+
+// A Watch delivers readiness events for a descriptor to a Handler. Each
+// Watch is one shot: after delivering an Event it is disarmed until Modify
+// arms it again.
+type Watch struct {
+	fd      uintptr
+	p       *poller
+	handler Handler
+
+	mu       sync.Mutex // protects interest, added, stopped and queue
+	interest Interest   // zero while disarmed
+	added    bool       // whether fd is in the epoll set
+	stopped  bool
+	queue    []Event       // waiting for room in a Chan, sent by flush
+	quit     chan struct{} // closed by Stop, abandons queue
+	quitOnce sync.Once
+}
+
+func (p *poller) Watch(fd uintptr, interest Interest, h Handler) (*Watch, error) {
+	w := &Watch{
+		fd:      fd,
+		p:       p,
+		handler: h,
+		quit:    make(chan struct{}),
+	}
+	p.mu.Lock()
+	if err := p.available(fd); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	p.watches[fd] = w
+	p.mu.Unlock()
+	if err := w.Modify(interest); err != nil {
+		w.Stop()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Modify sets the Watch's interest and arms it. A zero interest disarms
+// the Watch.
+func (w *Watch) Modify(interest Interest) error {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return errStopped
+	}
+	w.interest = interest
+	w.mu.Unlock()
+	return w.p.watch(w)
+}
+
+// Stop removes the Watch from the Poller, and discards any Events queued
+// for a Chan. Stop does not wait for a Handler which is already running.
+// When the Poller stops, each Watch is stopped, and its Handler receives a
+// final Event with Error set.
+func (w *Watch) Stop() {
+	w.quitOnce.Do(func() { close(w.quit) })
+	p := w.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.watches[w.fd] != w {
+		return
+	}
+	delete(p.watches, w.fd)
+	w.mu.Lock()
+	w.stopped = true
+	w.queue = nil
+	w.mu.Unlock()
+	p.unwatch(w)
+}
+
+// armed returns the interest the Watch is armed for, zero if it is
+// disarmed or stopped.
+func (w *Watch) armed() Interest {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return 0
+	}
+	return w.interest
+}
+
+// fire disarms the Watch and reports whether it was armed.
+func (w *Watch) fire() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	armed := w.interest != 0 && !w.stopped
+	w.interest = 0
+	return armed
+}
+
+// handle passes e to the Watch's Handler. An Event for a Chan is sent
+// without blocking if nothing is queued ahead of it and the channel has
+// room, and is queued for flush otherwise.
+func (w *Watch) handle(e Event) {
+	c, ok := w.handler.(Chan)
+	if !ok {
+		w.handler.Handle(e)
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.queue) == 0 {
+		select {
+		case c <- e:
+			return
+		default:
+		}
+	}
+	w.queue = append(w.queue, e)
+	if len(w.queue) == 1 {
+		go w.flush(c)
+	}
+}
+
+// flush sends the queued Events on c until the queue is empty, or the
+// Watch is stopped.
+func (w *Watch) flush(c Chan) {
+	w.mu.Lock()
+	for len(w.queue) > 0 {
+		e := w.queue[0]
+		w.mu.Unlock()
+		select {
+		case c <- e:
+		case <-w.quit:
+			return
+		}
+		w.mu.Lock()
+		if len(w.queue) > 0 {
+			w.queue = w.queue[1:]
+		}
+	}
+	w.mu.Unlock()
+}
+
+// A delivery is an Event waiting for its Handler to be called.
+type delivery struct {
+	w *Watch
+	e Event
+}
+
+// deliver calls the Handlers of the events queued by dispatch. It is
+// called by the loop without p.mu held, so a Handler may Modify or Stop
+// its Watch.
+func (p *poller) deliver() {
+	for i, d := range p.pending {
+		d.w.mu.Lock()
+		stopped := d.w.stopped
+		d.w.mu.Unlock()
+		if !stopped {
+			d.w.handle(d.e)
+		}
+		p.pending[i] = delivery{}
+	}
+	p.pending = p.pending[:0]
+}
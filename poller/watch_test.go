@@ -0,0 +1,246 @@
+package poller
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+// rawPipe returns both ends of a non-blocking pipe.
+func rawPipe(t *testing.T) (r, w int) {
+	var fds [2]int
+	if err := syscall.Pipe2(fds[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	return fds[0], fds[1]
+}
+
+// nextEvent returns the next Event sent on c, failing t if none arrives.
+func nextEvent(t *testing.T, c <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-c:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+// noEvent fails t if an Event is sent on c.
+func noEvent(t *testing.T, c <-chan Event) {
+	t.Helper()
+	select {
+	case e := <-c:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWatchReadable(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		defer syscall.Close(w)
+		c := make(chan Event, 1)
+		wt, err := p.Watch(uintptr(r), Readable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		noEvent(t, c)
+		syscall.Write(w, []byte("x"))
+		if got, want := nextEvent(t, c), (Event{Fd: uintptr(r), Readable: true}); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		// the Watch is disarmed until modified, even though r is
+		// still readable.
+		noEvent(t, c)
+		if err := wt.Modify(Readable); err != nil {
+			t.Fatal(err)
+		}
+		if e := nextEvent(t, c); !e.Readable {
+			t.Fatalf("got %+v, want readable", e)
+		}
+	})
+}
+
+func TestWatchHangup(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		if _, ok := p.(*poller).backend.(selectBackend); ok {
+			t.Skip("select does not report hangups")
+		}
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		c := make(chan Event, 1)
+		wt, err := p.Watch(uintptr(r), Readable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		syscall.Close(w)
+		if e := nextEvent(t, c); !e.Hangup {
+			t.Fatalf("got %+v, want hangup", e)
+		}
+	})
+}
+
+func TestWatchHandlerModify(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		defer syscall.Close(w)
+		done := make(chan []byte)
+		var got []byte
+		var wt *Watch
+		h := HandlerFunc(func(e Event) {
+			buf := make([]byte, 1)
+			if n, _ := syscall.Read(r, buf); n == 1 {
+				got = append(got, buf[0])
+			}
+			if len(got) == 3 {
+				done <- got
+				return
+			}
+			wt.Modify(Readable)
+		})
+		var err error
+		wt, err = p.Watch(uintptr(r), 0, h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		syscall.Write(w, []byte("abc"))
+		if err := wt.Modify(Readable); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case got := <-done:
+			if string(got) != "abc" {
+				t.Fatalf("got %q, want %q", got, "abc")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out")
+		}
+	})
+}
+
+func TestWatchStop(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		defer syscall.Close(w)
+		c := make(chan Event, 1)
+		wt, err := p.Watch(uintptr(w), Writable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e := nextEvent(t, c); !e.Writable {
+			t.Fatalf("got %+v, want writable", e)
+		}
+		wt.Stop()
+		if err := wt.Modify(Writable); err != errStopped {
+			t.Fatalf("Modify: got %v, want %v", err, errStopped)
+		}
+		noEvent(t, c)
+		// the descriptor may be watched again once stopped.
+		wt, err = p.Watch(uintptr(w), Writable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		nextEvent(t, c)
+	})
+}
+
+func TestWatchRegistered(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := pipe(t, p)
+		defer r.Close()
+		defer w.Close()
+		fd := r.(*rwc).ReadWriteCloser.(*pollDesc).fd
+		if _, err := p.Watch(fd, Readable, Chan(nil)); err != errRegistered {
+			t.Fatalf("Watch: got %v, want %v", err, errRegistered)
+		}
+		r2, w2 := rawPipe(t)
+		defer syscall.Close(r2)
+		defer syscall.Close(w2)
+		wt, err := p.Watch(uintptr(r2), Readable, Chan(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		if _, err := p.Register(rawFd(r2)); err != errRegistered {
+			t.Fatalf("Register: got %v, want %v", err, errRegistered)
+		}
+	})
+}
+
+func TestWatchClose(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		defer syscall.Close(w)
+		// nobody receives from unbuffered yet, so its Event is queued
+		// rather than blocking the Poller.
+		unbuffered := make(chan Event)
+		if _, err := p.Watch(uintptr(w), Writable, Chan(unbuffered)); err != nil {
+			t.Fatal(err)
+		}
+		c := make(chan Event, 1)
+		wt, err := p.Watch(uintptr(r), Readable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond) // let the writable Event be queued
+		closed := make(chan error)
+		go func() { closed <- p.Close() }()
+		select {
+		case err := <-closed:
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Close blocked")
+		}
+		if got, want := nextEvent(t, c), (Event{Fd: uintptr(r), Error: true}); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		// the queued Events arrive in order.
+		if got, want := nextEvent(t, unbuffered), (Event{Fd: uintptr(w), Writable: true}); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		if got, want := nextEvent(t, unbuffered), (Event{Fd: uintptr(w), Error: true}); got != want {
+			t.Fatalf("got %+v, want %+v", got, want)
+		}
+		if err := wt.Modify(Readable); err != errStopped {
+			t.Fatalf("Modify: got %v, want %v", err, errStopped)
+		}
+	})
+}
+
+func TestWatchChanFull(t *testing.T) {
+	forEachPoller(t, func(t *testing.T, p Poller) {
+		r, w := rawPipe(t)
+		defer syscall.Close(r)
+		defer syscall.Close(w)
+		c := make(chan Event)
+		wt, err := p.Watch(uintptr(r), Readable, Chan(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer wt.Stop()
+		syscall.Write(w, []byte("x"))
+		time.Sleep(10 * time.Millisecond) // let the Event be queued
+		// the one shot Event is delivered late rather than lost.
+		if e := nextEvent(t, c); !e.Readable {
+			t.Fatalf("got %+v, want readable", e)
+		}
+		if err := wt.Modify(Readable); err != nil {
+			t.Fatal(err)
+		}
+		if e := nextEvent(t, c); !e.Readable {
+			t.Fatalf("got %+v, want readable", e)
+		}
+	})
+}